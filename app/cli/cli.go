@@ -3,23 +3,90 @@ package cli
 import (
 	"context"
 
+	"github.com/Brahma-fi/brahma-builder/app/replay"
 	"github.com/Brahma-fi/brahma-builder/app/scheduler"
+	"github.com/Brahma-fi/brahma-builder/app/webhook"
+	"github.com/Brahma-fi/brahma-builder/app/worker"
 	"github.com/Brahma-fi/brahma-builder/app/worker/base"
-	"github.com/Brahma-fi/brahma-builder/app/worker/morpho"
 	"github.com/Brahma-fi/brahma-builder/internal/entity"
 	"github.com/urfave/cli/v3"
 )
 
 func BuildCLI() *cli.Command {
 	var executorID string
+	var executorIDs []string
+	var dryRun bool
+	var planFormat, planPath string
+	var replayOpts replay.Options
 	return &cli.Command{
 		Commands: []*cli.Command{
 			{
 				Name:  "scheduler",
-				Usage: "Runs sync scheduler",
+				Usage: "Schedules the subscription sync workflow",
 				Action: func(_ context.Context, _ *cli.Command) error {
 					return scheduler.Run()
 				},
+				Commands: []*cli.Command{
+					{
+						Name:  "plan",
+						Usage: "Prints the schedule mutations a sync would apply, without applying them",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:        "format",
+								Destination: &planFormat,
+								Value:       scheduler.PlanFormatTable,
+								Usage:       "table or json, a json plan can be applied with scheduler apply",
+							},
+						},
+						Action: func(_ context.Context, _ *cli.Command) error {
+							return scheduler.Plan(planFormat)
+						},
+					},
+					{
+						Name:  "apply",
+						Usage: "Applies exactly the schedule mutations of a json plan",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:        "plan",
+								Destination: &planPath,
+								Required:    true,
+								Usage:       "path of the plan printed by scheduler plan --format json",
+							},
+						},
+						Action: func(_ context.Context, _ *cli.Command) error {
+							return scheduler.Apply(planPath)
+						},
+					},
+				},
+			},
+			{
+				Name:  "sync",
+				Usage: "Runs a single subscription sync in process",
+				Action: func(_ context.Context, _ *cli.Command) error {
+					return scheduler.SyncOnce()
+				},
+			},
+			{
+				Name:  "migrate-schedules",
+				Usage: "Moves existing schedules to subscription scoped schedule IDs",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:        "dry-run",
+						Destination: &dryRun,
+						Usage:       "only log the schedules that would be migrated",
+					},
+				},
+				Action: func(_ context.Context, _ *cli.Command) error {
+					return scheduler.MigrateSchedules(dryRun)
+				},
+			},
+			{
+				Name:    "webhook-server",
+				Aliases: []string{"webhook"},
+				Usage:   "Runs the console task webhook server",
+				Action: func(_ context.Context, _ *cli.Command) error {
+					return webhook.Run()
+				},
 			},
 			{
 				Name:    "base-worker",
@@ -29,6 +96,47 @@ func BuildCLI() *cli.Command {
 					return base.Run()
 				},
 			},
+			{
+				Name:  "replay",
+				Usage: "Replays workflow histories against the current code, failing on non-deterministic changes",
+				Flags: []cli.Flag{
+					&cli.StringSliceFlag{
+						Name:        "file",
+						Destination: &replayOpts.Files,
+						Usage:       "history exported with temporal workflow show --output json, repeatable",
+					},
+					&cli.StringFlag{
+						Name:        "query",
+						Destination: &replayOpts.Query,
+						Value:       replay.DefaultQuery,
+						Usage:       "visibility query of the runs to download, when no file is given",
+					},
+					&cli.IntFlag{
+						Name:        "limit",
+						Destination: &replayOpts.Limit,
+						Value:       replay.DefaultLimit,
+						Usage:       "maximum number of runs to download",
+					},
+				},
+				Action: func(_ context.Context, _ *cli.Command) error {
+					return replay.Run(replayOpts)
+				},
+			},
+			{
+				Name:  "worker",
+				Usage: "Runs the strategy workers of one or more executors",
+				Flags: []cli.Flag{
+					&cli.StringSliceFlag{
+						Name:        "id",
+						Destination: &executorIDs,
+						Required:    true,
+						Usage:       "executor id, repeat to host several executors",
+					},
+				},
+				Action: func(_ context.Context, _ *cli.Command) error {
+					return worker.Run(executorIDs)
+				},
+			},
 			{
 				Name:    "morpho-worker",
 				Aliases: []string{"morpho"},
@@ -42,7 +150,7 @@ func BuildCLI() *cli.Command {
 				},
 				Usage: "Runs morpho worker",
 				Action: func(_ context.Context, _ *cli.Command) error {
-					return morpho.Run(executorID)
+					return worker.Run([]string{executorID})
 				},
 			},
 		},
@@ -0,0 +1,178 @@
+package replay
+
+import (
+	"context"
+	"fmt"
+
+	strategyworker "github.com/Brahma-fi/brahma-builder/app/worker"
+	"github.com/Brahma-fi/brahma-builder/config"
+	"github.com/Brahma-fi/brahma-builder/internal/usecase/workflows"
+	"github.com/Brahma-fi/brahma-builder/internal/usecase/workflows/activities"
+	"github.com/Brahma-fi/brahma-builder/pkg/log"
+	"github.com/Brahma-fi/brahma-builder/pkg/temporal"
+	"github.com/Brahma-fi/brahma-builder/pkg/vault"
+	"go.temporal.io/api/workflowservice/v1"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/worker"
+	"go.temporal.io/sdk/workflow"
+)
+
+const (
+	// DefaultQuery selects the runs of every workflow of the builder
+	DefaultQuery = "WorkflowType IN ('OrchestratorWorkflow', 'SyncWorkflow', 'MutexWorkflow')"
+	DefaultLimit = 100
+)
+
+type Options struct {
+	// Files are histories exported with `temporal workflow show --output json`, Query is ignored when set
+	Files []string
+	// Query selects the runs whose histories are downloaded from Temporal
+	Query string
+	Limit int64
+}
+
+// Run replays histories against the current workflow code and fails when any of them is no longer
+// deterministic, which would break the runs in flight once deployed.
+func Run(opts Options) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	logger := log.NewLogger("replay", "info")
+
+	vaultCli, err := vault.New(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := vaultCli.RunLifetimeWatcher(logger); err != nil {
+		return err
+	}
+
+	defer vaultCli.StopTokenRenew()
+	cfg := &config.Config{}
+	if err = vault.LoadConfig(cfg, vaultCli); err != nil {
+		return err
+	}
+
+	replayer, err := newReplayer(cfg)
+	if err != nil {
+		return err
+	}
+
+	temporalLogger := log.NewTemporalLoggerFromExisting(logger)
+	total, failed := 0, 0
+	if len(opts.Files) != 0 {
+		for _, file := range opts.Files {
+			total++
+			if err = replayer.ReplayWorkflowHistoryFromJSONFile(temporalLogger, file); err != nil {
+				failed++
+				logger.Error("replay failed", log.Str("file", file), log.Err(err))
+				continue
+			}
+			logger.Info("replayed", log.Str("file", file))
+		}
+	} else {
+		temporalClient, err := temporal.NewClient(ctx, cfg.TemporalConfig, temporalLogger)
+		if err != nil {
+			return fmt.Errorf("failed to create temporal client: %w", err)
+		}
+		defer temporalClient.Close()
+
+		executions, err := listExecutions(ctx, temporalClient, opts.Query, int(opts.Limit))
+		if err != nil {
+			return err
+		}
+
+		for _, execution := range executions {
+			total++
+			if err = replayer.ReplayWorkflowExecution(
+				ctx,
+				temporalClient.WorkflowService(),
+				temporalLogger,
+				cfg.TemporalConfig.TemporalNameSpace,
+				execution,
+			); err != nil {
+				failed++
+				logger.Error(
+					"replay failed",
+					log.Str("workflowID", execution.ID),
+					log.Str("runID", execution.RunID),
+					log.Err(err),
+				)
+				continue
+			}
+			logger.Info("replayed", log.Str("workflowID", execution.ID), log.Str("runID", execution.RunID))
+		}
+	}
+
+	logger.Info("replay done", log.Int("total", total), log.Int("failed", failed))
+	if failed != 0 {
+		return fmt.Errorf("%d of %d histories failed to replay", failed, total)
+	}
+
+	return nil
+}
+
+// newReplayer registers the workflows as the base worker does. Replays never run activities, which
+// are only needed for their options.
+func newReplayer(cfg *config.Config) (worker.WorkflowReplayer, error) {
+	strategyRegistry, err := strategyworker.StrategyRegistry()
+	if err != nil {
+		return nil, err
+	}
+
+	orchestrator := workflows.NewOrchestrator(
+		activities.NewContextActivity(nil),
+		activities.NewTaskActivity(nil, nil),
+		activities.NewStateActivity(nil),
+		activities.NewLockActivity(nil),
+		cfg.NewExecutorConfigRepo(),
+		strategyRegistry,
+		false,
+	)
+	subscriptionSync := workflows.NewSubscriptionSync(activities.NewSyncActivity(nil))
+
+	replayer := worker.NewWorkflowReplayer()
+	replayer.RegisterWorkflow(orchestrator.OrchestratorWorkflow)
+	replayer.RegisterWorkflow(subscriptionSync.SyncWorkflow)
+	replayer.RegisterWorkflow(workflows.SubAccountMutex{}.MutexWorkflow)
+	return replayer, nil
+}
+
+// listExecutions returns up to limit runs matching query, most recent first
+func listExecutions(ctx context.Context, cli client.Client, query string, limit int) ([]workflow.Execution, error) {
+	if query == "" {
+		query = DefaultQuery
+	}
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+
+	executions := make([]workflow.Execution, 0, limit)
+	var nextPageToken []byte
+	for {
+		resp, err := cli.ListWorkflow(ctx, &workflowservice.ListWorkflowExecutionsRequest{
+			PageSize:      int32(min(limit, 1000)),
+			NextPageToken: nextPageToken,
+			Query:         query,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list workflows: %w", err)
+		}
+
+		for _, info := range resp.GetExecutions() {
+			executions = append(executions, workflow.Execution{
+				ID:    info.GetExecution().GetWorkflowId(),
+				RunID: info.GetExecution().GetRunId(),
+			})
+			if len(executions) == limit {
+				return executions, nil
+			}
+		}
+
+		nextPageToken = resp.GetNextPageToken()
+		if len(nextPageToken) == 0 {
+			return executions, nil
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Brahma-fi/brahma-builder/internal/entity"
+	"github.com/Brahma-fi/brahma-builder/internal/usecase/services"
+)
+
+const (
+	PlanFormatTable = "table"
+	PlanFormatJSON  = "json"
+)
+
+// Plan prints the mutations a sync would apply, without touching any schedule
+func Plan(format string) error {
+	if format != PlanFormatTable && format != PlanFormatJSON {
+		return fmt.Errorf("unknown plan format %q, expected %s or %s", format, PlanFormatTable, PlanFormatJSON)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	scheduler, _, closeFn, err := newScheduler(ctx)
+	if err != nil {
+		return err
+	}
+	defer closeFn()
+
+	plan, err := scheduler.Plan(ctx)
+	if err != nil {
+		return err
+	}
+
+	if format == PlanFormatJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(plan)
+	}
+
+	return printPlan(os.Stdout, plan)
+}
+
+// Apply applies exactly the mutations of the plan stored at planPath, as printed by Plan in json
+func Apply(planPath string) error {
+	data, err := os.ReadFile(planPath)
+	if err != nil {
+		return fmt.Errorf("failed to read plan: %w", err)
+	}
+
+	plan := &entity.SyncPlan{}
+	if err = json.Unmarshal(data, plan); err != nil {
+		return fmt.Errorf("failed to decode plan: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	scheduler, _, closeFn, err := newScheduler(ctx)
+	if err != nil {
+		return err
+	}
+	defer closeFn()
+
+	if err = scheduler.EnsureSearchAttributes(ctx); err != nil {
+		return err
+	}
+
+	report, err := scheduler.Apply(ctx, plan)
+	if err != nil {
+		return err
+	}
+
+	services.LogSyncReport(ctx, report)
+	if len(report.Failed) != 0 {
+		return fmt.Errorf("%d of %d mutations failed", len(report.Failed), len(plan.Mutations()))
+	}
+
+	return nil
+}
+
+func printPlan(w io.Writer, plan *entity.SyncPlan) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "CHAIN\tACTION\tSCHEDULE\tSUBACCOUNT\tSUBSCRIPTION\tSPEC\tREASON")
+	for _, chain := range plan.Chains {
+		for _, m := range chain.Mutations {
+			fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\t%s\n",
+				chain.ChainID, m.Type, m.ScheduleID, m.Key.SubAccountAddress.Hex(), m.Key.SubscriptionID,
+				describeSpec(m.Config), m.Reason)
+		}
+
+		if chain.Report == nil {
+			continue
+		}
+
+		for _, e := range chain.Report.Skipped {
+			fmt.Fprintf(tw, "%d\tskip\t%s\t%s\t%s\t-\t%s\n",
+				chain.ChainID, e.ScheduleID, e.Key.SubAccountAddress.Hex(), e.Key.SubscriptionID, e.Reason)
+		}
+
+		for _, e := range chain.Report.Failed {
+			fmt.Fprintf(tw, "%d\tfail\t%s\t%s\t%s\t-\t%s\n",
+				chain.ChainID, e.ScheduleID, e.Key.SubAccountAddress.Hex(), e.Key.SubscriptionID, e.Reason)
+		}
+	}
+
+	for _, e := range plan.Failed {
+		fmt.Fprintf(tw, "%d\tfail\t-\t-\t-\t-\texecutor %s: %s\n", e.Key.ChainID, e.Key.ExecutorAddress.Hex(), e.Reason)
+	}
+
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	_, err := fmt.Fprintf(w, "\n%d mutations planned at %s\n", len(plan.Mutations()), plan.CreatedAt.Format(time.RFC3339))
+	return err
+}
+
+// describeSpec summarises the schedule spec a create or update mutation targets
+func describeSpec(config *entity.ExecuteWorkflowParams) string {
+	if config == nil || config.Schedule == nil {
+		return "-"
+	}
+
+	parts := make([]string, 0)
+	if config.Schedule.Every > 0 {
+		parts = append(parts, "every "+config.Schedule.Every.String())
+	}
+
+	for _, expr := range config.Schedule.Cron {
+		parts = append(parts, "cron "+expr)
+	}
+
+	if len(config.Schedule.Calendars) != 0 {
+		parts = append(parts, fmt.Sprintf("%d calendars", len(config.Schedule.Calendars)))
+	}
+
+	if config.Schedule.EndAt != nil {
+		parts = append(parts, "until "+config.Schedule.EndAt.Format(time.RFC3339))
+	}
+
+	return strings.Join(parts, ", ")
+}
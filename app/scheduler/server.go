@@ -2,10 +2,6 @@ package scheduler
 
 import (
 	"context"
-	"fmt"
-	"os"
-	"os/signal"
-	"syscall"
 	"time"
 
 	"github.com/Brahma-fi/brahma-builder/config"
@@ -18,73 +14,103 @@ import (
 	"github.com/Brahma-fi/brahma-builder/pkg/vault"
 )
 
+// Run ensures the subscription sync schedule exists and runs every SyncSubscriptionsEvery,
+// the sync itself runs as a workflow on the base worker.
 func Run() error {
-	var err error
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	logger := log.NewLogger("sync-scheduler", "info")
-
-	vaultCli, err := vault.New(ctx)
+	scheduler, cfg, closeFn, err := newScheduler(ctx)
 	if err != nil {
 		return err
 	}
+	defer closeFn()
 
-	if err := vaultCli.RunLifetimeWatcher(logger); err != nil {
+	if err = scheduler.EnsureSearchAttributes(ctx); err != nil {
 		return err
 	}
 
-	defer vaultCli.StopTokenRenew()
-	cfg := &config.Config{}
-	if err = vault.LoadConfig(cfg, vaultCli); err != nil {
+	every, err := time.ParseDuration(cfg.SyncSubscriptionsEvery)
+	if err != nil {
 		return err
 	}
 
-	temporalClient, err := temporal.NewClient(ctx, cfg.TemporalConfig, log.NewTemporalLoggerFromExisting(logger))
+	return scheduler.EnsureSyncSchedule(ctx, every)
+}
+
+// SyncOnce runs a single subscription sync in process, outside of the sync schedule
+func SyncOnce() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	scheduler, _, closeFn, err := newScheduler(ctx)
 	if err != nil {
 		return err
 	}
-	defer temporalClient.Close()
+	defer closeFn()
 
-	console := integration.NewConsoleClient(cfg.ConsoleBaseURL)
+	if err = scheduler.EnsureSearchAttributes(ctx); err != nil {
+		return err
+	}
 
-	executors := entity.NewExecutorConfigRepo(cfg.ExecutorConfig)
+	_, err = scheduler.Sync(ctx)
+	return err
+}
 
-	schedulesRepo := repo.NewSchedulesRepo(temporalClient)
+// MigrateSchedules moves existing schedules to the IDs derived from OrchestratorParams.ID
+func MigrateSchedules(dryRun bool) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 
-	scheduler, err := services.NewScheduler(ctx, temporalClient, console, executors, schedulesRepo)
+	scheduler, _, closeFn, err := newScheduler(ctx)
 	if err != nil {
 		return err
 	}
+	defer closeFn()
 
-	sync, err := time.ParseDuration(cfg.SyncSubscriptionsEvery)
+	if !dryRun {
+		if err = scheduler.EnsureSearchAttributes(ctx); err != nil {
+			return err
+		}
+	}
+
+	return scheduler.MigrateScheduleIDs(ctx, dryRun)
+}
+
+func newScheduler(ctx context.Context) (*services.Scheduler, *config.Config, func(), error) {
+	logger := log.NewLogger("sync-scheduler", "info")
+
+	vaultCli, err := vault.New(ctx)
 	if err != nil {
-		return err
+		return nil, nil, nil, err
 	}
 
-	sch := time.Tick(sync)
+	if err := vaultCli.RunLifetimeWatcher(logger); err != nil {
+		return nil, nil, nil, err
+	}
 
-	// Waiting signal
-	interrupt := make(chan os.Signal, 1)
-	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+	cfg := &config.Config{}
+	if err = vault.LoadConfig(cfg, vaultCli); err != nil {
+		vaultCli.StopTokenRenew()
+		return nil, nil, nil, err
+	}
 
-	if err = scheduler.Sync(ctx); err != nil {
-		return err
+	temporalClient, err := temporal.NewClient(ctx, cfg.TemporalConfig, log.NewTemporalLoggerFromExisting(logger))
+	if err != nil {
+		vaultCli.StopTokenRenew()
+		return nil, nil, nil, err
 	}
 
-	for {
-		select {
-		case <-ctx.Done():
-			fmt.Println("manager - Run - context canceled")
-			return nil
-		case s := <-interrupt:
-			fmt.Println("manager - Run - signal: " + s.String())
-			return nil
-		case <-sch:
-			if err = scheduler.Sync(ctx); err != nil {
-				fmt.Println("failed to call sync", err)
-			}
-		}
+	closeFn := func() {
+		temporalClient.Close()
+		vaultCli.StopTokenRenew()
 	}
 
+	console := integration.NewConsoleClient(cfg.ConsoleBaseURL)
+
+	executors := entity.NewExecutorConfigRepo(cfg.ExecutorConfig)
+
+	schedulesRepo := repo.NewSchedulesRepo(temporalClient)
+
+	return services.NewScheduler(temporalClient, console, executors, schedulesRepo), cfg, closeFn, nil
 }
@@ -0,0 +1,67 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/Brahma-fi/brahma-builder/internal/entity"
+	"github.com/Brahma-fi/brahma-builder/internal/usecase/services"
+	"github.com/Brahma-fi/brahma-builder/pkg/log"
+	"github.com/labstack/echo/v4"
+	"go.temporal.io/api/serviceerror"
+)
+
+type signaler interface {
+	SignalWorkflow(ctx context.Context, workflowID string, runID string, signalName string, arg interface{}) error
+}
+
+type verifier interface {
+	Verify(workflowID, runID, token string) bool
+}
+
+// _taskRoute is the path of the URLs handed out by services.Webhooks.TaskURL
+const _taskRoute = services.WebhookTaskPath + "/:workflowID/:runID/:token"
+
+type taskHandler struct {
+	signaler signaler
+	verifier verifier
+}
+
+// taskUpdate turns a console task callback into a task update signal of the workflow run the URL was issued for
+func (h *taskHandler) taskUpdate(c echo.Context) error {
+	ctx := c.Request().Context()
+	logger := log.GetLogger(ctx)
+	workflowID, runID := c.Param("workflowID"), c.Param("runID")
+	if !h.verifier.Verify(workflowID, runID, c.Param("token")) {
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid token")
+	}
+
+	status := entity.TaskStatus{}
+	if err := c.Bind(&status); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid task status")
+	}
+
+	if status.TaskID == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "missing task id")
+	}
+
+	err := h.signaler.SignalWorkflow(ctx, workflowID, runID, entity.SignalTaskUpdate, status)
+	var notFound *serviceerror.NotFound
+	switch {
+	case errors.As(err, &notFound):
+		// the run already completed, the console has nobody left to notify
+		return echo.NewHTTPError(http.StatusGone, "execution completed")
+	case err != nil:
+		logger.Error("failed to signal task update", log.Str("workflowID", workflowID), log.Err(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to deliver task update")
+	}
+
+	logger.Info(
+		"delivered task update",
+		log.Str("workflowID", workflowID),
+		log.Str("taskID", status.TaskID),
+		log.Str("status", string(status.Status)),
+	)
+	return c.NoContent(http.StatusAccepted)
+}
@@ -0,0 +1,59 @@
+package webhook
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Brahma-fi/brahma-builder/internal/usecase/services"
+	"github.com/labstack/echo/v4"
+)
+
+// fakeSignaler records the workflow runs it was asked to signal
+type fakeSignaler struct {
+	signaled []string
+}
+
+func (f *fakeSignaler) SignalWorkflow(_ context.Context, workflowID, runID, _ string, _ interface{}) error {
+	f.signaled = append(f.signaled, workflowID+"/"+runID)
+	return nil
+}
+
+func TestTaskUpdateIsAuthenticatedByTheCallbackURL(t *testing.T) {
+	const baseURL = "https://webhooks.example"
+	webhooks := services.NewWebhooks(baseURL, "secret")
+	callback := strings.TrimPrefix(webhooks.TaskURL("workflow", "run"), baseURL)
+
+	tests := []struct {
+		name string
+		path string
+		want int
+	}{
+		{name: "issued url", path: callback, want: http.StatusAccepted},
+		{name: "without token", path: services.WebhookTaskPath + "/workflow/run", want: http.StatusNotFound},
+		{name: "token of another run", path: strings.Replace(callback, "/run/", "/other/", 1), want: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signaler := &fakeSignaler{}
+			e := echo.New()
+			e.POST(_taskRoute, (&taskHandler{signaler: signaler, verifier: webhooks}).taskUpdate)
+
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(`{"taskId":"task"}`))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Fatalf("got status %d, want %d", rec.Code, tt.want)
+			}
+
+			if signaled := len(signaler.signaled) != 0; signaled != (tt.want == http.StatusAccepted) {
+				t.Fatalf("got signaled %v for status %d", signaler.signaled, rec.Code)
+			}
+		})
+	}
+}
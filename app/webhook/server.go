@@ -0,0 +1,96 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/Brahma-fi/brahma-builder/config"
+	"github.com/Brahma-fi/brahma-builder/internal/usecase/services"
+	"github.com/Brahma-fi/brahma-builder/pkg/log"
+	"github.com/Brahma-fi/brahma-builder/pkg/temporal"
+	"github.com/Brahma-fi/brahma-builder/pkg/vault"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	_defaultHostPort = ":8080"
+	_shutdownTimeout = 10 * time.Second
+)
+
+// Run serves the console task callbacks on HostPort and forwards them to the waiting orchestrator runs
+func Run() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	logger := log.NewLogger("webhook-server", "info")
+
+	vaultCli, err := vault.New(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := vaultCli.RunLifetimeWatcher(logger); err != nil {
+		return err
+	}
+
+	defer vaultCli.StopTokenRenew()
+	cfg := &config.Config{}
+	if err = vault.LoadConfig(cfg, vaultCli); err != nil {
+		return err
+	}
+
+	if cfg.WebhookSecret == "" {
+		return errors.New("webhook secret is not configured")
+	}
+
+	temporalClient, err := temporal.NewClient(ctx, cfg.TemporalConfig, log.NewTemporalLoggerFromExisting(logger))
+	if err != nil {
+		return fmt.Errorf("failed to create temporal client: %w", err)
+	}
+	defer temporalClient.Close()
+
+	handler := &taskHandler{
+		signaler: temporalClient,
+		verifier: services.NewWebhooks(cfg.WebhookBaseURL, cfg.WebhookSecret),
+	}
+
+	e := echo.New()
+	e.HideBanner = true
+	e.GET("/health", func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	})
+	e.POST(_taskRoute, handler.taskUpdate)
+
+	hostPort := cfg.HostPort
+	if hostPort == "" {
+		hostPort = _defaultHostPort
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- e.Start(hostPort)
+	}()
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+
+	select {
+	case err = <-errCh:
+		if !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		return nil
+	case s := <-interrupt:
+		fmt.Println("webhook - Run - signal: " + s.String())
+	}
+
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), _shutdownTimeout)
+	defer shutdownCancel()
+	return e.Shutdown(shutdownCtx)
+}
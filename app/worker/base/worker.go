@@ -2,13 +2,19 @@ package base
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 
+	strategyworker "github.com/Brahma-fi/brahma-builder/app/worker"
 	"github.com/Brahma-fi/brahma-builder/config"
 	"github.com/Brahma-fi/brahma-builder/internal/entity"
+	"github.com/Brahma-fi/brahma-builder/internal/repo"
+	integration "github.com/Brahma-fi/brahma-builder/internal/usecase/integrations"
+	"github.com/Brahma-fi/brahma-builder/internal/usecase/services"
 	"github.com/Brahma-fi/brahma-builder/internal/usecase/workflows"
 	"github.com/Brahma-fi/brahma-builder/internal/usecase/workflows/activities"
 	"github.com/Brahma-fi/brahma-builder/pkg/log"
+	"github.com/Brahma-fi/brahma-builder/pkg/rpc"
 	"github.com/Brahma-fi/brahma-builder/pkg/temporal"
 	"github.com/Brahma-fi/brahma-builder/pkg/vault"
 	"go.temporal.io/sdk/worker"
@@ -42,20 +48,93 @@ func Run() error {
 	}
 	defer temporalClient.Close()
 
+	rpcWithFallback, err := rpc.NewRPC(cfg.ChainID2RPCURLs)
+	if err != nil {
+		return fmt.Errorf("failed to create rpc clients: %w", err)
+	}
+
+	console := integration.NewConsoleClient(cfg.ConsoleBaseURL)
 	ctxActivity := activities.NewContextActivity(temporalClient.ScheduleClient())
+	taskActivity := activities.NewTaskActivity(console, rpcWithFallback)
+
+	stateActivity, closeStorage, err := newStateActivity(ctx, cfg.StorageDSN)
+	if err != nil {
+		return err
+	}
+	defer closeStorage()
+
+	if cfg.StorageDSN == "" {
+		logger.Warn("storageDSN not set, execution state is kept in memory and lost on restart")
+	}
+
+	scheduler := services.NewScheduler(
+		temporalClient,
+		console,
+		cfg.NewExecutorConfigRepo(),
+		repo.NewSchedulesRepo(temporalClient),
+	)
+	// the sync workflow creates and updates schedules indexed by them
+	if err = scheduler.EnsureSearchAttributes(ctx); err != nil {
+		return fmt.Errorf("failed to register search attributes: %w", err)
+	}
+
+	syncActivity := activities.NewSyncActivity(scheduler)
+	subscriptionSync := workflows.NewSubscriptionSync(syncActivity)
+
+	lockActivity := activities.NewLockActivity(temporalClient)
+	subAccountMutex := workflows.SubAccountMutex{}
+
+	strategyRegistry, err := strategyworker.StrategyRegistry()
+	if err != nil {
+		return err
+	}
 
 	orchestratorActivity := workflows.NewOrchestrator(
 		ctxActivity,
+		taskActivity,
+		stateActivity,
+		lockActivity,
 		cfg.NewExecutorConfigRepo(),
+		strategyRegistry,
+		services.NewWebhooks(cfg.WebhookBaseURL, cfg.WebhookSecret).Enabled(),
 	)
 
 	return temporal.RunWorkflow(
 		temporalClient,
 		entity.BaseTaskQueue,
 		worker.Options{},
-		orchestratorActivity.OrchestratorWorkflow,
+		[]any{
+			orchestratorActivity.OrchestratorWorkflow,
+			subscriptionSync.SyncWorkflow,
+			subAccountMutex.MutexWorkflow,
+		},
 		[]any{
 			ctxActivity.GetExecutionContext,
+			taskActivity.AwaitTaskConfirmation,
+			stateActivity.LatestState,
+			stateActivity.SaveExecution,
+			lockActivity.RequestLock,
+			syncActivity.SyncTargets,
+			syncActivity.SyncExecutor,
 		},
 	)
 }
+
+// newStateActivity keeps execution state in the database at dsn, or in memory when dsn is empty
+func newStateActivity(ctx context.Context, dsn string) (*activities.StateActivity, func(), error) {
+	if dsn == "" {
+		return activities.NewStateActivity(repo.NewMemoryExecutionsLogRepo()), func() {}, nil
+	}
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open storage: %w", err)
+	}
+
+	if err = db.PingContext(ctx); err != nil {
+		_ = db.Close()
+		return nil, nil, fmt.Errorf("failed to connect to storage: %w", err)
+	}
+
+	return activities.NewStateActivity(repo.NewExecutionsLogRepo(db)), func() { _ = db.Close() }, nil
+}
@@ -4,99 +4,80 @@ import (
 	"context"
 	"fmt"
 
-	"github.com/Brahma-fi/brahma-builder/config"
+	"github.com/Brahma-fi/brahma-builder/app/worker/strategy"
+	"github.com/Brahma-fi/brahma-builder/internal/entity"
 	"github.com/Brahma-fi/brahma-builder/internal/usecase/integrations"
+	"github.com/Brahma-fi/brahma-builder/internal/usecase/oracle"
 	"github.com/Brahma-fi/brahma-builder/internal/usecase/services"
 	"github.com/Brahma-fi/brahma-builder/internal/usecase/workflows/activities/morpho"
-	"github.com/Brahma-fi/brahma-builder/pkg/log"
-	"github.com/Brahma-fi/brahma-builder/pkg/rpc"
-	"github.com/Brahma-fi/brahma-builder/pkg/temporal"
-	"github.com/Brahma-fi/brahma-builder/pkg/vault"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
-	"go.temporal.io/sdk/worker"
 )
 
-func Run(id string) error {
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
-	logger := log.NewLogger("morpho-worker", "info")
-
-	vaultCli, err := vault.New(ctx)
-	if err != nil {
-		return err
-	}
-
-	if err := vaultCli.RunLifetimeWatcher(logger); err != nil {
-		return err
-	}
-
-	defer vaultCli.StopTokenRenew()
-	cfg := &config.Config{}
-	if err = vault.LoadConfig(cfg, vaultCli); err != nil {
-		return err
-	}
-
-	temporalClient, err := temporal.NewClient(ctx, cfg.TemporalConfig, log.NewTemporalLoggerFromExisting(logger))
-	if err != nil {
-		return fmt.Errorf("failed to create temporal client: %w", err)
-	}
-	defer temporalClient.Close()
-
-	rpcWithFallback, err := rpc.NewRPC(cfg.ChainID2RPCURLs)
+// New builds the morpho rebalancer activities of executorConfig
+func New(ctx context.Context, deps strategy.Deps, executorConfig *entity.ExecutorConfig) ([]any, error) {
+	rebalancer, err := morpho.Rebalancer(executorConfig.ID)
 	if err != nil {
-		return fmt.Errorf("failed to connect to base client: %w", err)
-	}
-
-	console := integrations.NewConsoleClient(cfg.ConsoleBaseURL)
-	executorConfig, err := cfg.NewExecutorConfigRepo().ByID(id)
-	if err != nil {
-		return fmt.Errorf("failed to fetch morpho config: %w", err)
+		return nil, err
 	}
 
 	strategyConfig, err := morpho.ParseConfig(executorConfig.StrategyConfig)
 	if err != nil {
-		return fmt.Errorf("failed to parse morpho startegy config: %w", err)
+		return nil, fmt.Errorf("failed to parse morpho startegy config: %w", err)
 	}
 
-	baseClient, err := rpcWithFallback.RetryableClient(executorConfig.ChainID)
+	baseClient, err := deps.RPC.RetryableClient(executorConfig.ChainID)
 	if err != nil {
-		return fmt.Errorf("failed to connect to base client: %w", err)
+		return nil, fmt.Errorf("failed to connect to base client: %w", err)
 	}
 
 	morphoClient := integrations.NewMorphoClient(strategyConfig.BaseURL, baseClient)
 	executor, err := services.NewConsoleExecutor(ctx,
 		common.HexToAddress(executorConfig.Address),
 		executorConfig.ChainID,
-		rpcWithFallback,
-		vault.NewKeyManager(vaultCli, "console-kernel"),
-		console,
+		deps.RPC,
+		deps.KeyManager,
+		deps.Console,
 		common.HexToAddress(executorConfig.Signer),
-		common.HexToAddress(cfg.ExecutorPluginAddress),
+		common.HexToAddress(deps.Config.ExecutorPluginAddress),
+		deps.Webhooks,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create console executor: %w", err)
+	}
+
+	chainlink, err := oracle.NewChainlinkFromRPC(deps.RPC, deps.Config.PriceFeeds)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create chainlink oracle: %w", err)
+	}
+
+	pricingOracle, err := oracle.NewComposite(
+		deps.Config.Oracle,
+		oracle.NewTokenDecimals(map[int64]bind.ContractCaller{executorConfig.ChainID: baseClient}),
+		oracle.NewStatic(deps.Config.Oracle.StaticPrices),
+		chainlink,
+		oracle.NewMorpho(morphoClient),
 	)
 	if err != nil {
-		return fmt.Errorf("failed to create console executor: %w", err)
+		return nil, fmt.Errorf("failed to create pricing oracle: %w", err)
 	}
 
 	activity, err := morpho.NewReBalancingStrategy(
 		morphoClient,
 		executor,
 		baseClient,
-		// logs repo, check interface for impl
-		nil,
 		strategyConfig,
-		// pricing oracle, check interface for impl
-		nil,
+		pricingOracle,
+		baseClient,
+		common.HexToAddress(deps.Config.ExecutorPluginAddress),
 	)
 	if err != nil {
-		return fmt.Errorf("failed to create morpho activity: %w", err)
+		return nil, fmt.Errorf("failed to create morpho activity: %w", err)
 	}
 
-	return temporal.RunWorkflow(
-		temporalClient,
-		executorConfig.TaskQueue,
-		worker.Options{},
-		nil,
-		[]any{activity.ExecutionHandler},
-	)
+	return []any{
+		rebalancer.Activity(activity.ExecutionHandler),
+		// runs started before the strategy had its own activity name still schedule the legacy one
+		rebalancer.LegacyActivity(activity.ExecutionHandler),
+	}, nil
 }
@@ -0,0 +1,26 @@
+package worker
+
+import (
+	"github.com/Brahma-fi/brahma-builder/app/worker/morpho"
+	"github.com/Brahma-fi/brahma-builder/app/worker/strategy"
+	"github.com/Brahma-fi/brahma-builder/internal/usecase/strategies"
+	morphoactivity "github.com/Brahma-fi/brahma-builder/internal/usecase/workflows/activities/morpho"
+)
+
+// Strategies lists every strategy workers can host, a new strategy only needs its line here
+func Strategies() []strategy.Registration {
+	return []strategy.Registration{
+		{Strategy: morphoactivity.RebalancerMainnet, New: morpho.New},
+		{Strategy: morphoactivity.RebalancerBase, New: morpho.New},
+	}
+}
+
+// StrategyRegistry is the registry the orchestrator resolves the strategies of Strategies from
+func StrategyRegistry() (*strategies.Registry, error) {
+	return strategy.Registry(Strategies())
+}
+
+// Run hosts the strategies of the executors ids
+func Run(ids []string) error {
+	return strategy.Run(ids, Strategies())
+}
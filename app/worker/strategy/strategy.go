@@ -0,0 +1,142 @@
+package strategy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Brahma-fi/brahma-builder/config"
+	"github.com/Brahma-fi/brahma-builder/internal/entity"
+	"github.com/Brahma-fi/brahma-builder/internal/usecase/integrations"
+	"github.com/Brahma-fi/brahma-builder/internal/usecase/services"
+	"github.com/Brahma-fi/brahma-builder/internal/usecase/strategies"
+	"github.com/Brahma-fi/brahma-builder/pkg/log"
+	"github.com/Brahma-fi/brahma-builder/pkg/rpc"
+	"github.com/Brahma-fi/brahma-builder/pkg/temporal"
+	"github.com/Brahma-fi/brahma-builder/pkg/vault"
+	"go.temporal.io/sdk/worker"
+)
+
+// Deps are the dependencies shared by every strategy hosted by a worker
+type Deps struct {
+	Config     *config.Config
+	RPC        *rpc.RPC
+	Console    *integrations.ConsoleClient
+	KeyManager *vault.KeyManager
+	Webhooks   *services.Webhooks
+}
+
+// Constructor builds the activities of a strategy for one of its executors
+type Constructor func(ctx context.Context, deps Deps, executorConfig *entity.ExecutorConfig) ([]any, error)
+
+// Registration pairs a strategy with the constructor of its activities
+type Registration struct {
+	Strategy strategies.Strategy
+	New      Constructor
+}
+
+// Registry builds the strategy registry the orchestrator resolves strategies from
+func Registry(registrations []Registration) (*strategies.Registry, error) {
+	s := make([]strategies.Strategy, len(registrations))
+	for i, r := range registrations {
+		s[i] = r.Strategy
+	}
+
+	return strategies.NewRegistry(s...)
+}
+
+// Run hosts the strategies of the executors ids, each on its executor's task queue
+func Run(ids []string, registrations []Registration) error {
+	if len(ids) == 0 {
+		return errors.New("no executor id to run")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	logger := log.NewLogger("strategy-worker", "info")
+
+	vaultCli, err := vault.New(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := vaultCli.RunLifetimeWatcher(logger); err != nil {
+		return err
+	}
+
+	defer vaultCli.StopTokenRenew()
+	cfg := &config.Config{}
+	if err = vault.LoadConfig(cfg, vaultCli); err != nil {
+		return err
+	}
+
+	temporalClient, err := temporal.NewClient(ctx, cfg.TemporalConfig, log.NewTemporalLoggerFromExisting(logger))
+	if err != nil {
+		return fmt.Errorf("failed to create temporal client: %w", err)
+	}
+	defer temporalClient.Close()
+
+	rpcWithFallback, err := rpc.NewRPC(cfg.ChainID2RPCURLs)
+	if err != nil {
+		return fmt.Errorf("failed to create rpc clients: %w", err)
+	}
+
+	deps := Deps{
+		Config:     cfg,
+		RPC:        rpcWithFallback,
+		Console:    integrations.NewConsoleClient(cfg.ConsoleBaseURL),
+		KeyManager: vault.NewKeyManager(vaultCli, "console-kernel"),
+		Webhooks:   services.NewWebhooks(cfg.WebhookBaseURL, cfg.WebhookSecret),
+	}
+
+	constructors := make(map[string]Constructor, len(registrations))
+	for _, r := range registrations {
+		constructors[r.Strategy.StrategyID()] = r.New
+	}
+
+	executors := cfg.NewExecutorConfigRepo()
+	taskQueues := make(map[string]string)
+	workers := make([]worker.Worker, 0, len(ids))
+	for _, id := range ids {
+		executorConfig, err := executors.ByID(id)
+		if err != nil {
+			return fmt.Errorf("failed to fetch executor config %s: %w", id, err)
+		}
+
+		newActivities, ok := constructors[id]
+		if !ok {
+			return fmt.Errorf("no strategy registered for executor %s", id)
+		}
+
+		// activities are registered by strategy activity name, two executors on a queue would collide
+		if other, ok := taskQueues[executorConfig.TaskQueue]; ok {
+			return fmt.Errorf("executors %s and %s share task queue %s", other, id, executorConfig.TaskQueue)
+		}
+		taskQueues[executorConfig.TaskQueue] = id
+
+		activities, err := newActivities(ctx, deps, executorConfig)
+		if err != nil {
+			return fmt.Errorf("failed to create %s activities: %w", id, err)
+		}
+
+		workers = append(workers, temporal.NewWorker(temporalClient, executorConfig.TaskQueue, worker.Options{}, nil, activities))
+	}
+
+	for i, w := range workers {
+		if err = w.Start(); err != nil {
+			for _, started := range workers[:i] {
+				started.Stop()
+			}
+			return err
+		}
+		logger.Info("started strategy worker", log.Str("executorID", ids[i]))
+	}
+
+	<-worker.InterruptCh()
+	for _, w := range workers {
+		w.Stop()
+	}
+
+	return nil
+}
@@ -20,6 +20,14 @@ type Config struct {
 	ExecutorPluginAddress  string                 `json:"executorPluginAddress" envconfig:"EXECUTOR_PLUGIN_ADDRESS"`
 	ServiceName            string                 `json:"serviceName" envconfig:"SERVICE_NAME"`
 	HostPort               string                 `json:"hostPort" envconfig:"HOST_PORT"`
+	// WebhookBaseURL is the public URL the console reaches the webhook server at, no webhooks are sent when empty
+	WebhookBaseURL string `json:"webhookBaseURL" envconfig:"WEBHOOK_BASE_URL"`
+	WebhookSecret  string `json:"webhookSecret" envconfig:"WEBHOOK_SECRET"`
+	// StorageDSN is the postgres database execution state is kept in, in memory when empty
+	StorageDSN string `json:"storageDSN" envconfig:"STORAGE_DSN"`
+	// PriceFeeds are the USD feeds USD denominated fees are converted with
+	PriceFeeds entity.PriceFeedConfigs `json:"priceFeeds" envconfig:"PRICE_FEEDS"`
+	Oracle     entity.OracleConfig     `json:"oracle" envconfig:"ORACLE"`
 }
 
 func (c Config) NewExecutorConfigRepo() entity.ExecutorConfigRepo {
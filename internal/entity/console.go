@@ -35,24 +35,36 @@ type GetExecutorMetadataResp struct {
 }
 
 type ClientSubscription struct {
-	ChainId           int               `json:"chainId"`
-	CommitHash        string            `json:"commitHash"`
-	CreatedAt         time.Time         `json:"createdAt"`
-	Duration          int               `json:"duration"`
-	FeeAmount         string            `json:"feeAmount"`
-	FeeToken          string            `json:"feeToken"`
-	Id                string            `json:"id"`
-	Metadata          json.RawMessage   `json:"metadata"`
-	RegistryId        string            `json:"registryId"`
-	Status            int               `json:"status"`
-	SubAccountAddress string            `json:"subAccountAddress"`
-	TokenInputs       map[string]string `json:"tokenInputs"`
-	TokenLimits       map[string]string `json:"tokenLimits"`
+	ChainId           int                `json:"chainId"`
+	CommitHash        string             `json:"commitHash"`
+	CreatedAt         time.Time          `json:"createdAt"`
+	Duration          int                `json:"duration"`
+	FeeAmount         string             `json:"feeAmount"`
+	FeeToken          string             `json:"feeToken"`
+	Id                string             `json:"id"`
+	Metadata          json.RawMessage    `json:"metadata"`
+	RegistryId        string             `json:"registryId"`
+	Status            SubscriptionStatus `json:"status"`
+	SubAccountAddress string             `json:"subAccountAddress"`
+	TokenInputs       map[string]string  `json:"tokenInputs"`
+	TokenLimits       map[string]string  `json:"tokenLimits"`
+}
+
+// ExpiresAt returns when the subscription runs out, Duration being in seconds.
+// Subscriptions without a duration never expire.
+func (c ClientSubscription) ExpiresAt() (time.Time, bool) {
+	if c.Duration <= 0 || c.CreatedAt.IsZero() {
+		return time.Time{}, false
+	}
+
+	return c.CreatedAt.Add(time.Duration(c.Duration) * time.Second).UTC(), true
 }
 
 type GetClientSubscriptionsResp struct {
-	Data  []ClientSubscription `json:"data"`
-	Error string               `json:"error"`
+	Data []ClientSubscription `json:"data"`
+	// NextCursor fetches the next page, empty on the last page
+	NextCursor string `json:"nextCursor"`
+	Error      string `json:"error"`
 }
 
 type VerifyExecutableReq struct {
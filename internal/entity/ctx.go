@@ -9,6 +9,29 @@ type ScheduleCtx struct {
 	RunningExecutionWorkflowIDs []string  `json:"runningExecutionWorkflowIDs"`
 }
 
+// CustomIntervalOptions is the schedule part of the subscription metadata.
+// Every, Cron and Calendars are combined, an action is taken at the union of their times.
 type CustomIntervalOptions struct {
-	Every string `json:"Every"` // Duration which can be parsed using time.ParseDuration or number in seconds
+	Every     string            `json:"Every"`     // Duration which can be parsed using time.ParseDuration or number in seconds
+	Jitter    string            `json:"Jitter"`    // Duration, every action is delayed by a random amount up to it
+	Cron      []string          `json:"Cron"`      // Cron expressions with 5 to 7 fields, or descriptors like @daily
+	Calendars []CalendarOptions `json:"Calendars"` // Calendar based times, e.g. every weekday at 14:00
+	Exclude   []CalendarOptions `json:"Exclude"`   // Calendar based times at which no action is taken
+	StartAt   string            `json:"StartAt"`   // RFC3339 time, no action is taken before it
+	EndAt     string            `json:"EndAt"`     // RFC3339 time, no action is taken after it
+	TimeZone  string            `json:"TimeZone"`  // IANA time zone name of Cron and Calendars, defaults to UTC
+}
+
+// CalendarOptions matches times by their calendar fields. Every field is a comma separated list
+// of values, ranges and steps, e.g. "1-5", "*/15" or "0,30". Second, Minute and Hour default to 0,
+// the remaining fields default to "*".
+type CalendarOptions struct {
+	Second     string `json:"Second"`
+	Minute     string `json:"Minute"`
+	Hour       string `json:"Hour"`
+	DayOfMonth string `json:"DayOfMonth"`
+	Month      string `json:"Month"`
+	Year       string `json:"Year"`
+	DayOfWeek  string `json:"DayOfWeek"` // 0-6, 0 is Sunday
+	Comment    string `json:"Comment"`
 }
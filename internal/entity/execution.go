@@ -0,0 +1,125 @@
+package entity
+
+import "encoding/json"
+
+// SignalTaskUpdate carries the console's TaskStatus callbacks to the orchestrator run awaiting the task
+const SignalTaskUpdate = "task-update"
+
+// TaskState is the state of a task relayed through the console
+type TaskState string
+
+const (
+	TaskStatePending    TaskState = "pending"
+	TaskStateExecuting  TaskState = "executing"
+	TaskStateSuccessful TaskState = "successful"
+	TaskStateFailed     TaskState = "failed"
+	TaskStateCancelled  TaskState = "cancelled"
+)
+
+// IsTerminal is true once the console stops working on the task
+func (s TaskState) IsTerminal() bool {
+	switch s {
+	case TaskStateSuccessful, TaskStateFailed, TaskStateCancelled:
+		return true
+	default:
+		return false
+	}
+}
+
+type TaskStatus struct {
+	TaskID string    `json:"taskId"`
+	Status TaskState `json:"status"`
+	// TxHash is set once the console submitted the task's transaction
+	TxHash string `json:"txHash"`
+	Error  string `json:"error"`
+}
+
+type GetTaskStatusResp struct {
+	Data  TaskStatus `json:"data"`
+	Error string     `json:"error"`
+}
+
+// TxStatus is the on chain outcome of an execution's transaction
+type TxStatus string
+
+const (
+	// TxStatusNone is an execution which had nothing to submit
+	TxStatusNone      TxStatus = "none"
+	TxStatusConfirmed TxStatus = "confirmed"
+	TxStatusReverted  TxStatus = "reverted"
+	// TxStatusFailed is a task the console gave up on before submitting a transaction
+	TxStatusFailed TxStatus = "failed"
+	// TxStatusDropped is a submitted transaction which never made it into a block
+	TxStatusDropped TxStatus = "dropped"
+)
+
+// ExecutionAction is what a strategy decided to do on an execution, strategies define their own actions
+type ExecutionAction string
+
+// ExecutionActionNone is an execution which decided to leave the position as is
+const ExecutionActionNone ExecutionAction = "none"
+
+// ExecutionResult is the outcome of a single execution of a subscription
+type ExecutionResult struct {
+	Status      ExecutionStatus `json:"status"`
+	Action      ExecutionAction `json:"action"`
+	TaskID      string          `json:"taskID,omitempty"`
+	ChainID     int64           `json:"chainID"`
+	TxHash      string          `json:"txHash,omitempty"`
+	BlockNumber uint64          `json:"blockNumber,omitempty"`
+	GasUsed     uint64          `json:"gasUsed,omitempty"`
+	TxStatus    TxStatus        `json:"txStatus"`
+	// Amounts are the amounts the action moved keyed by their role, e.g. input or fees, in token base units
+	Amounts map[string]string `json:"amounts,omitempty"`
+	// Reason is why the strategy decided on Action
+	Reason  string `json:"reason,omitempty"`
+	Message string `json:"message,omitempty"`
+	// State is persisted once the execution completes and handed back to the strategy on the next one
+	State json.RawMessage `json:"state,omitempty"`
+}
+
+// Outcome makes ExecutionResult, and any strategy result embedding it, a strategy result
+func (r ExecutionResult) Outcome() ExecutionResult {
+	return r
+}
+
+// ExecutionPhase is the step an orchestrator run is at
+type ExecutionPhase string
+
+const (
+	ExecutionPhaseFetchingContext ExecutionPhase = "fetching_context"
+	// ExecutionPhaseGuarded is a run waiting on an operator while other executions of its schedule are running
+	ExecutionPhaseGuarded ExecutionPhase = "guarded"
+	// ExecutionPhaseLocking is a run waiting on its sub-account's mutex
+	ExecutionPhaseLocking      ExecutionPhase = "locking"
+	ExecutionPhaseExecuting    ExecutionPhase = "executing"
+	ExecutionPhaseAwaitingTask ExecutionPhase = "awaiting_task"
+	ExecutionPhaseCompleted    ExecutionPhase = "completed"
+	ExecutionPhaseSkipped      ExecutionPhase = "skipped"
+	ExecutionPhaseAborted      ExecutionPhase = "aborted"
+	ExecutionPhaseFailed       ExecutionPhase = "failed"
+)
+
+// Orchestrator queries, answered at any point of a run
+const (
+	QueryPhase          = "phase"
+	QueryLastError      = "last-error"
+	QueryScheduleCtx    = "schedule-ctx"
+	QueryStrategyOutput = "strategy-output"
+)
+
+// Orchestrator signals, each carrying an OperatorSignal
+const (
+	// SignalSkip completes the run without executing the strategy, unless it already started executing
+	SignalSkip = "skip"
+	// SignalAbort cancels whatever the run is doing and fails it
+	SignalAbort = "abort"
+	// SignalForceExecute executes the strategy even though other executions of the schedule are running
+	SignalForceExecute = "force-execute"
+)
+
+// OperatorSignal is an operator's intervention in a run
+type OperatorSignal struct {
+	Operator string `json:"operator"`
+	Reason   string `json:"reason"`
+}
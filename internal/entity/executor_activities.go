@@ -9,12 +9,10 @@ const (
 	DefaultNamespace = "brahma-builder"
 )
 
-func ExecutionHandler(_ ExecCtx) error {
-	return nil
-}
-
 type ExecCtx struct {
 	ScheduleCtx
 	ExecuteWorkflowParams
 	TriggeredAt time.Time
+	// PrevState is the state the strategy recorded on the subscription's last completed execution, nil on the first
+	PrevState *ExecutionState
 }
@@ -2,26 +2,54 @@ package entity
 
 import (
 	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"go.temporal.io/api/enums/v1"
 	"go.temporal.io/sdk/temporal"
 	"go.temporal.io/sdk/workflow"
 )
 
 type ExecutorConfig struct {
-	ActivityTimeout      string         `json:"activityTimeout"`
-	TaskQueue            string         `json:"taskQueue"`
-	RetryAttempts        uint           `json:"retryAttempts"`
-	MaximumRetryInterval string         `json:"maximumRetryInterval"`
-	ChainID              int64          `json:"chainId"`
-	Address              string         `json:"address"`
-	Signer               string         `json:"signer"`
-	Every                string         `json:"every"`
-	StrategyConfig       map[string]any `json:"strategyConfig"`
-	ID                   string         `json:"Id"`
+	ActivityTimeout      string `json:"activityTimeout"`
+	TaskQueue            string `json:"taskQueue"`
+	RetryAttempts        uint   `json:"retryAttempts"`
+	MaximumRetryInterval string `json:"maximumRetryInterval"`
+	ChainID              int64  `json:"chainId"`
+	Address              string `json:"address"`
+	Signer               string `json:"signer"`
+	Every                string `json:"every"`
+	// GracePeriod keeps schedules running for this long past their subscription's expiry
+	GracePeriod string `json:"gracePeriod"`
+	// WindDownBefore adds a final run this long before the subscription expires, none when empty
+	WindDownBefore string `json:"windDownBefore"`
+	// Overlap is the schedule overlap policy, e.g. Skip or BUFFER_ONE, defaults to Skip
+	Overlap string `json:"overlap"`
+	// CatchupWindow is how late a missed run may still start, Temporal's default when empty
+	CatchupWindow  string `json:"catchupWindow"`
+	PauseOnFailure bool   `json:"pauseOnFailure"`
+	// TriggerImmediately runs a new schedule as soon as it is created, defaults to true
+	TriggerImmediately *bool `json:"triggerImmediately"`
+	// Jitter is the default schedule jitter, subscriptions may override it
+	Jitter string `json:"jitter"`
+	// LockTimeout is how long a run waits on its sub-account's mutex, 15m when empty
+	LockTimeout string `json:"lockTimeout"`
+	// LockLease is how long a run holds its sub-account's mutex at most, 1h when empty
+	LockLease string `json:"lockLease"`
+	// GuardTimeout is how long a run waits on an operator to force or skip it while other executions of
+	// its schedule are running, after which it skips itself. Runs are not guarded when empty.
+	GuardTimeout   string         `json:"guardTimeout"`
+	StrategyConfig map[string]any `json:"strategyConfig"`
+	ID             string         `json:"Id"`
 }
 
+const (
+	_defaultLockTimeout = 15 * time.Minute
+	_defaultLockLease   = time.Hour
+)
+
 func (e ExecutorConfig) ActivityOptions() (workflow.ActivityOptions, error) {
 	interval, err := time.ParseDuration(e.MaximumRetryInterval)
 	if err != nil {
@@ -43,6 +71,88 @@ func (e ExecutorConfig) ActivityOptions() (workflow.ActivityOptions, error) {
 	}, nil
 }
 
+// SubscriptionWindow returns the grace period and wind down offset of the executor's subscriptions,
+// zero when not configured.
+func (e ExecutorConfig) SubscriptionWindow() (gracePeriod time.Duration, windDownBefore time.Duration, err error) {
+	if e.GracePeriod != "" {
+		if gracePeriod, err = time.ParseDuration(e.GracePeriod); err != nil {
+			return 0, 0, err
+		}
+	}
+
+	if e.WindDownBefore != "" {
+		if windDownBefore, err = time.ParseDuration(e.WindDownBefore); err != nil {
+			return 0, 0, err
+		}
+	}
+
+	return gracePeriod, windDownBefore, nil
+}
+
+// SchedulePolicies returns the policies of the executor's subscription schedules
+func (e ExecutorConfig) SchedulePolicies() (SchedulePolicies, error) {
+	policies := SchedulePolicies{
+		Overlap:            enums.SCHEDULE_OVERLAP_POLICY_SKIP,
+		PauseOnFailure:     e.PauseOnFailure,
+		TriggerImmediately: e.TriggerImmediately == nil || *e.TriggerImmediately,
+	}
+
+	if e.Overlap != "" {
+		overlap, err := enums.ScheduleOverlapPolicyFromString(e.Overlap)
+		if err != nil {
+			if overlap, err = enums.ScheduleOverlapPolicyFromString(
+				"SCHEDULE_OVERLAP_POLICY_" + strings.ToUpper(e.Overlap),
+			); err != nil {
+				return SchedulePolicies{}, fmt.Errorf("invalid overlap policy %q", e.Overlap)
+			}
+		}
+		policies.Overlap = overlap
+	}
+
+	var err error
+	if e.CatchupWindow != "" {
+		if policies.CatchupWindow, err = time.ParseDuration(e.CatchupWindow); err != nil {
+			return SchedulePolicies{}, err
+		}
+	}
+
+	if e.Jitter != "" {
+		if policies.Jitter, err = time.ParseDuration(e.Jitter); err != nil {
+			return SchedulePolicies{}, err
+		}
+	}
+
+	return policies, nil
+}
+
+// GuardOptions returns how long the executor's runs wait on an operator while other executions are running,
+// zero when they are not guarded
+func (e ExecutorConfig) GuardOptions() (timeout time.Duration, err error) {
+	if e.GuardTimeout == "" {
+		return 0, nil
+	}
+
+	return time.ParseDuration(e.GuardTimeout)
+}
+
+// LockOptions returns how long the executor's runs wait on and hold their sub-account's mutex
+func (e ExecutorConfig) LockOptions() (timeout time.Duration, lease time.Duration, err error) {
+	timeout, lease = _defaultLockTimeout, _defaultLockLease
+	if e.LockTimeout != "" {
+		if timeout, err = time.ParseDuration(e.LockTimeout); err != nil {
+			return 0, 0, err
+		}
+	}
+
+	if e.LockLease != "" {
+		if lease, err = time.ParseDuration(e.LockLease); err != nil {
+			return 0, 0, err
+		}
+	}
+
+	return timeout, lease, nil
+}
+
 type ExecutorConfigs []ExecutorConfig
 
 type ExecutorConfigRepo struct {
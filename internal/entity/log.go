@@ -2,11 +2,15 @@ package entity
 
 import (
 	"database/sql/driver"
+	"encoding/json"
+	"errors"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+var ErrExecutionLogNotFound = errors.New("execution log not found")
+
 type Jsonb driver.Value
 
 type Log struct {
@@ -19,3 +23,22 @@ type Log struct {
 	OutputTxnHash     string    `db:"output_txn" json:"OutputTxnHash"`
 	CreatedAt         time.Time `db:"created_at" json:"createdAt"`
 }
+
+// ExecutionState is the state a strategy recorded on the latest completed execution of a subscription
+type ExecutionState struct {
+	Metadata  json.RawMessage `json:"metadata"`
+	TxHash    string          `json:"txHash"`
+	CreatedAt time.Time       `json:"createdAt"`
+}
+
+// LogCursor is the position of the last log of a page, pages are ordered newest first
+type LogCursor struct {
+	CreatedAt time.Time `json:"createdAt"`
+	ID        uuid.UUID `json:"id"`
+}
+
+type LogPage struct {
+	Logs []Log `json:"logs"`
+	// Next is the cursor of the following page, nil on the last page
+	Next *LogCursor `json:"next,omitempty"`
+}
@@ -20,8 +20,9 @@ type VaultInfo struct {
 		Address  string `json:"address"`
 	} `json:"asset"`
 	State struct {
-		Apy    float64 `json:"apy"`
-		NetApy float64 `json:"netApy"`
+		Apy         float64    `json:"apy"`
+		NetApy      float64    `json:"netApy"`
+		TotalAssets JsonBigInt `json:"totalAssets"`
 	} `json:"state"`
 	Liquidity struct {
 		Underlying JsonBigInt `json:"underlying"`
@@ -46,8 +47,9 @@ type VaultQuery struct {
 				Address  graphql.String
 			}
 			State struct {
-				APY    graphql.Float
-				NetAPY graphql.Float
+				APY         graphql.Float
+				NetAPY      graphql.Float
+				TotalAssets JsonBigInt
 			}
 			Liquidity struct {
 				Underlying JsonBigInt
@@ -87,11 +89,13 @@ func (q VaultQuery) ToVaultInfo() []VaultInfo {
 				Address:  string(item.Asset.Address),
 			},
 			State: struct {
-				Apy    float64 `json:"apy"`
-				NetApy float64 `json:"netApy"`
+				Apy         float64    `json:"apy"`
+				NetApy      float64    `json:"netApy"`
+				TotalAssets JsonBigInt `json:"totalAssets"`
 			}{
-				Apy:    float64(item.State.APY),
-				NetApy: float64(item.State.NetAPY),
+				Apy:         float64(item.State.APY),
+				NetApy:      float64(item.State.NetAPY),
+				TotalAssets: item.State.TotalAssets,
 			},
 			Liquidity: struct {
 				Underlying JsonBigInt `json:"underlying"`
@@ -105,6 +109,14 @@ func (q VaultQuery) ToVaultInfo() []VaultInfo {
 	return vaultInfos
 }
 
+type AssetPriceQuery struct {
+	AssetByAddress struct {
+		Address  graphql.String
+		Decimals graphql.Int
+		PriceUsd *graphql.Float
+	} `graphql:"assetByAddress(address: $address, chainId: $chainID)"`
+}
+
 type UserQuery struct {
 	Users struct {
 		Items []struct {
@@ -0,0 +1,40 @@
+package entity
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Sub-account mutex signals
+const (
+	// SignalLockAcquire queues a LockRequest on the mutex workflow
+	SignalLockAcquire = "lock-acquire"
+	// SignalLockRelease carries the owner run ID of a LockRequest, releasing the lock or withdrawing the request
+	SignalLockRelease = "lock-release"
+	// SignalLockGranted is sent by the mutex workflow to the owner of the LockRequest it granted
+	SignalLockGranted = "lock-granted"
+	// SignalLockRenew carries the owner run ID of the lock's holder, restarting its lease
+	SignalLockRenew = "lock-renew"
+)
+
+// LockRequest is an orchestrator run waiting on a sub-account's mutex
+type LockRequest struct {
+	OwnerWorkflowID string `json:"ownerWorkflowID"`
+	OwnerRunID      string `json:"ownerRunID"`
+	// Lease is how long the lock is held for at most, it is released once expired
+	Lease time.Duration `json:"lease"`
+}
+
+// LockGrant is the lock handed to a LockRequest's owner
+type LockGrant struct {
+	MutexWorkflowID string    `json:"mutexWorkflowID"`
+	OwnerRunID      string    `json:"ownerRunID"`
+	ExpiresAt       time.Time `json:"expiresAt"`
+}
+
+// MutexWorkflowID is the workflow guarding the executions of a sub-account on a chain
+func MutexWorkflowID(chainID int64, subAccount common.Address) string {
+	return fmt.Sprintf("mutex-%d-%s", chainID, subAccount.Hex())
+}
@@ -0,0 +1,35 @@
+package entity
+
+// PriceFeedConfig is the Chainlink style USD aggregator of a token on a chain
+type PriceFeedConfig struct {
+	ChainID int64  `json:"chainId"`
+	Token   string `json:"token"`
+	Feed    string `json:"feed"`
+	// TokenDecimals are read from the token when zero
+	TokenDecimals uint8 `json:"tokenDecimals"`
+	// MaxStaleness is how old the latest round may be, 25h when empty to cover daily heartbeats
+	MaxStaleness string `json:"maxStaleness"`
+	// MaxDeviation is the largest relative change from the previous round accepted, 0.1 when zero
+	MaxDeviation float64 `json:"maxDeviation"`
+}
+
+type PriceFeedConfigs []PriceFeedConfig
+
+// OracleConfig is the policy USD prices are aggregated from their sources with
+type OracleConfig struct {
+	// CacheTTL is how long a price is reused for a chain and token, 1m when empty
+	CacheTTL string `json:"cacheTTL"`
+	// MaxDeviation is the largest relative distance from the median a source is kept at, 0.05 when zero
+	MaxDeviation float64 `json:"maxDeviation"`
+	// MinSources is how many sources must agree on a price, 1 when zero. Rejecting an outlier takes three
+	// sources or more, without agreement no price is returned.
+	MinSources int `json:"minSources"`
+	// StaticPrices are only used when no other source prices a token
+	StaticPrices []StaticPriceConfig `json:"staticPrices"`
+}
+
+type StaticPriceConfig struct {
+	ChainID  int64   `json:"chainId"`
+	Token    string  `json:"token"`
+	PriceUSD float64 `json:"priceUSD"`
+}
@@ -3,10 +3,13 @@ package entity
 import (
 	"crypto/sha256"
 	"encoding/binary"
+	"encoding/json"
 	"fmt"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"go.temporal.io/api/enums/v1"
+	"go.temporal.io/sdk/client"
 )
 
 const (
@@ -14,6 +17,8 @@ const (
 	SearchAttrKeyExecutorAddress   = "executorAddress"
 	SearchAttrKeyChainID           = "chainID"
 	SearchAttrKeyExecutorID        = "executorID"
+	SearchAttrKeyConfigHash        = "configHash"
+	SearchAttrKeyExecutionStatus   = "ExecutionStatus"
 )
 
 type ExecuteWorkflowParams struct {
@@ -22,6 +27,18 @@ type ExecuteWorkflowParams struct {
 	Schedule *ScheduledWorkflowConfig `json:"schedule"`
 }
 
+// Hash returns a digest of the params a schedule was created or last updated with.
+// It is stored as a search attribute so drift can be detected while listing schedules,
+// since the schedule memo can not be updated once created.
+func (e ExecuteWorkflowParams) Hash() (string, error) {
+	raw, err := json.Marshal(e)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%x", sha256.Sum256(raw)), nil
+}
+
 type OrchestratorParams struct {
 	ExecutorAddress   common.Address     `json:"executorAddress"`
 	SubAccountAddress common.Address     `json:"subAccountAddress"`
@@ -30,15 +47,55 @@ type OrchestratorParams struct {
 	Subscription      ClientSubscription `json:"subscription"`
 }
 
+// ID is the schedule ID of the subscription, derived from everything that identifies it.
+// A sub-account can be subscribed to several executors, so the sub-account alone is not unique.
 func (o OrchestratorParams) ID() string {
 	var hashInput []byte
 	hashInput = append(hashInput, o.SubAccountAddress.Bytes()...)
 	hashInput = append(hashInput, o.ExecutorAddress.Bytes()...)
-	binary.LittleEndian.PutUint64(hashInput, uint64(o.ChainID))
+	hashInput = binary.LittleEndian.AppendUint64(hashInput, uint64(o.ChainID))
+	hashInput = append(hashInput, []byte(o.Subscription.Id)...)
 	return fmt.Sprintf("%x", sha256.Sum256(hashInput))
 }
 
+func (o OrchestratorParams) Key() ScheduleKey {
+	return ScheduleKey{
+		SubAccountAddress: o.SubAccountAddress,
+		ExecutorAddress:   o.ExecutorAddress,
+		ChainID:           o.ChainID,
+		SubscriptionID:    o.Subscription.Id,
+	}
+}
+
+// ScheduleKey identifies the schedule of a single subscription
+type ScheduleKey struct {
+	SubAccountAddress common.Address `json:"subAccountAddress"`
+	ExecutorAddress   common.Address `json:"executorAddress"`
+	ChainID           int64          `json:"chainID"`
+	SubscriptionID    string         `json:"subscriptionID"`
+}
+
 type ScheduledWorkflowConfig struct {
-	Every time.Duration `json:"every"`
-	ID    string        `json:"ID"`
+	Every     time.Duration                 `json:"every"`
+	Jitter    time.Duration                 `json:"jitter,omitempty"`
+	Cron      []string                      `json:"cron,omitempty"`
+	Calendars []client.ScheduleCalendarSpec `json:"calendars,omitempty"`
+	Skip      []client.ScheduleCalendarSpec `json:"skip,omitempty"`
+	StartAt   *time.Time                    `json:"startAt,omitempty"`
+	EndAt     *time.Time                    `json:"endAt,omitempty"`
+	TimeZone  string                        `json:"timeZone,omitempty"`
+	// WindDownAt is the time of the final run before the subscription expires, if the executor asks for one
+	WindDownAt *time.Time       `json:"windDownAt,omitempty"`
+	Policies   SchedulePolicies `json:"policies"`
+	ID         string           `json:"ID"`
+}
+
+// SchedulePolicies are the executor's policies applied to its subscription schedules
+type SchedulePolicies struct {
+	Overlap            enums.ScheduleOverlapPolicy `json:"overlap"`
+	CatchupWindow      time.Duration               `json:"catchupWindow,omitempty"`
+	PauseOnFailure     bool                        `json:"pauseOnFailure"`
+	TriggerImmediately bool                        `json:"triggerImmediately"`
+	// Jitter is the executor default, the schedule's own Jitter takes precedence
+	Jitter time.Duration `json:"jitter,omitempty"`
 }
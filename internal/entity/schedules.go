@@ -7,17 +7,24 @@ const (
 	StrategyIDMorphoRebalancerMainnet = "morpho-rebalancer-mainnet"
 )
 
+// ExecutionStatus is the status of an orchestrator run, kept in its SearchAttrKeyExecutionStatus search attribute
 type ExecutionStatus string
 
 const (
 	ExecutionStatusRunning   ExecutionStatus = "Running"
-	ExecutionStatusCompleted                 = "Completed"
-	ExecutionStatusFailed                    = "Failed"
-	ExecutionStatusCanceled                  = "Canceled"
+	ExecutionStatusCompleted ExecutionStatus = "Completed"
+	// ExecutionStatusSkipped is a run which completed without executing its strategy
+	ExecutionStatusSkipped  ExecutionStatus = "Skipped"
+	ExecutionStatusFailed   ExecutionStatus = "Failed"
+	ExecutionStatusCanceled ExecutionStatus = "Canceled"
 )
 
 type Schedule struct {
 	Config     ExecuteWorkflowParams
 	ScheduleID string
 	CreatedAt  time.Time
+	// ConfigHash is the hash of the params the schedule currently runs with
+	ConfigHash string
+	Paused     bool
+	Note       string
 }
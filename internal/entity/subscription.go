@@ -0,0 +1,70 @@
+package entity
+
+import "fmt"
+
+// SubscriptionStatus is the status of a ClientSubscription as reported by the console.
+// Statuses the console adds later are unknown, the schedule of a subscription in one is left as is.
+type SubscriptionStatus int
+
+const (
+	SubscriptionStatusActive    SubscriptionStatus = 2
+	SubscriptionStatusPaused    SubscriptionStatus = 3
+	SubscriptionStatusCancelled SubscriptionStatus = 4
+	SubscriptionStatusExpired   SubscriptionStatus = 5
+	SubscriptionStatusSuspended SubscriptionStatus = 6
+)
+
+// ScheduleAction is what the scheduler does with the schedule of a subscription
+type ScheduleAction string
+
+const (
+	// ScheduleActionNone leaves the schedule, or the lack of one, as is
+	ScheduleActionNone ScheduleAction = "none"
+	// ScheduleActionRun creates the schedule, or unpauses it if it was paused by the scheduler
+	ScheduleActionRun ScheduleAction = "run"
+	// ScheduleActionPause pauses the schedule
+	ScheduleActionPause ScheduleAction = "pause"
+	// ScheduleActionDelete deletes the schedule
+	ScheduleActionDelete ScheduleAction = "delete"
+)
+
+func (s SubscriptionStatus) ScheduleAction() ScheduleAction {
+	switch s {
+	case SubscriptionStatusActive:
+		return ScheduleActionRun
+	case SubscriptionStatusPaused, SubscriptionStatusSuspended:
+		return ScheduleActionPause
+	case SubscriptionStatusCancelled, SubscriptionStatusExpired:
+		return ScheduleActionDelete
+	default:
+		return ScheduleActionNone
+	}
+}
+
+// Known is false for statuses the console returns which have no name here
+func (s SubscriptionStatus) Known() bool {
+	switch s {
+	case SubscriptionStatusActive, SubscriptionStatusPaused, SubscriptionStatusCancelled,
+		SubscriptionStatusExpired, SubscriptionStatusSuspended:
+		return true
+	default:
+		return false
+	}
+}
+
+func (s SubscriptionStatus) String() string {
+	switch s {
+	case SubscriptionStatusActive:
+		return "active"
+	case SubscriptionStatusPaused:
+		return "paused"
+	case SubscriptionStatusCancelled:
+		return "cancelled"
+	case SubscriptionStatusExpired:
+		return "expired"
+	case SubscriptionStatusSuspended:
+		return "suspended"
+	default:
+		return fmt.Sprintf("unknown(%d)", int(s))
+	}
+}
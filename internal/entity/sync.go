@@ -0,0 +1,189 @@
+package entity
+
+import (
+	"slices"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// SubscriptionSnapshot is the state of every registered executor's subscriptions at the start of a sync
+type SubscriptionSnapshot struct {
+	Accounts         []ClientSubscription         `json:"accounts"`
+	ExecutorMetadata map[string]*ExecutorMetadata `json:"executorMetadata"`
+	// SyncedExecutors are the executors whose subscriptions were fetched, schedules of any other
+	// executor must not be deleted for missing a subscription
+	SyncedExecutors []common.Address `json:"syncedExecutors"`
+	// Failed are the executors whose subscriptions could not be fetched
+	Failed []SyncReportEntry `json:"failed,omitempty"`
+}
+
+// ChainIDs returns the chains of the snapshot's accounts in ascending order
+func (s *SubscriptionSnapshot) ChainIDs() []int64 {
+	chainIDs := make([]int64, 0)
+	for _, account := range s.Accounts {
+		if chainID := int64(account.ChainId); !slices.Contains(chainIDs, chainID) {
+			chainIDs = append(chainIDs, chainID)
+		}
+	}
+
+	slices.Sort(chainIDs)
+	return chainIDs
+}
+
+// ForChain returns the snapshot restricted to the accounts of chainID
+func (s *SubscriptionSnapshot) ForChain(chainID int64) *SubscriptionSnapshot {
+	accounts := make([]ClientSubscription, 0)
+	for _, account := range s.Accounts {
+		if int64(account.ChainId) == chainID {
+			accounts = append(accounts, account)
+		}
+	}
+
+	return &SubscriptionSnapshot{
+		Accounts:         accounts,
+		ExecutorMetadata: s.ExecutorMetadata,
+		SyncedExecutors:  s.SyncedExecutors,
+	}
+}
+
+// SyncTarget is an executor whose subscriptions are synced on their own, by a single activity
+type SyncTarget struct {
+	ExecutorAddress common.Address `json:"executorAddress"`
+	ChainID         int64          `json:"chainID"`
+}
+
+// SyncProgress is how far the sync of a SyncTarget got, heartbeated by the activity running it
+type SyncProgress struct {
+	Subscriptions int `json:"subscriptions"`
+	Mutations     int `json:"mutations"`
+	Applied       int `json:"applied"`
+}
+
+// ChainSyncPlan is the diff between the subscriptions and the schedules of a chain
+type ChainSyncPlan struct {
+	ChainID   int64              `json:"chainID"`
+	Mutations []ScheduleMutation `json:"mutations"`
+	// Report holds the subscriptions of the chain which were skipped or failed to diff
+	Report *SyncReport `json:"report"`
+}
+
+// SyncScheduleID is the ID of the schedule running the subscription sync workflow
+const SyncScheduleID = "subscription-sync"
+
+type ScheduleMutationType string
+
+const (
+	ScheduleMutationCreate  ScheduleMutationType = "create"
+	ScheduleMutationUpdate  ScheduleMutationType = "update"
+	ScheduleMutationPause   ScheduleMutationType = "pause"
+	ScheduleMutationUnpause ScheduleMutationType = "unpause"
+	ScheduleMutationDelete  ScheduleMutationType = "delete"
+)
+
+// ScheduleMutation is a change the scheduler applies to the schedule of a subscription
+type ScheduleMutation struct {
+	Type       ScheduleMutationType `json:"type"`
+	ScheduleID string               `json:"scheduleID"`
+	Key        ScheduleKey          `json:"key"`
+	// Config is the target params of create and update mutations
+	Config *ExecuteWorkflowParams `json:"config,omitempty"`
+	Reason string                 `json:"reason"`
+}
+
+type SyncReportEntry struct {
+	ScheduleID string      `json:"scheduleID,omitempty"`
+	Key        ScheduleKey `json:"key"`
+	Reason     string      `json:"reason"`
+}
+
+// SyncReport is the outcome of a single sync, for every subscription and schedule it touched
+type SyncReport struct {
+	StartedAt  time.Time         `json:"startedAt"`
+	FinishedAt time.Time         `json:"finishedAt"`
+	Created    []SyncReportEntry `json:"created"`
+	Updated    []SyncReportEntry `json:"updated"`
+	Paused     []SyncReportEntry `json:"paused"`
+	Unpaused   []SyncReportEntry `json:"unpaused"`
+	Deleted    []SyncReportEntry `json:"deleted"`
+	Skipped    []SyncReportEntry `json:"skipped"`
+	Failed     []SyncReportEntry `json:"failed"`
+}
+
+func NewSyncReport(startedAt time.Time) *SyncReport {
+	return &SyncReport{StartedAt: startedAt}
+}
+
+// Applied records the outcome of applying mutation, err being the error it failed with if any
+func (r *SyncReport) Applied(mutation ScheduleMutation, err error) {
+	entry := SyncReportEntry{ScheduleID: mutation.ScheduleID, Key: mutation.Key, Reason: mutation.Reason}
+	if err != nil {
+		entry.Reason = string(mutation.Type) + ": " + err.Error()
+		r.Failed = append(r.Failed, entry)
+		return
+	}
+
+	switch mutation.Type {
+	case ScheduleMutationCreate:
+		r.Created = append(r.Created, entry)
+	case ScheduleMutationUpdate:
+		r.Updated = append(r.Updated, entry)
+	case ScheduleMutationPause:
+		r.Paused = append(r.Paused, entry)
+	case ScheduleMutationUnpause:
+		r.Unpaused = append(r.Unpaused, entry)
+	case ScheduleMutationDelete:
+		r.Deleted = append(r.Deleted, entry)
+	}
+}
+
+func (r *SyncReport) Skip(entry SyncReportEntry) {
+	r.Skipped = append(r.Skipped, entry)
+}
+
+func (r *SyncReport) Fail(entry SyncReportEntry) {
+	r.Failed = append(r.Failed, entry)
+}
+
+// Merge appends every entry of other to the report
+func (r *SyncReport) Merge(other *SyncReport) {
+	if other == nil {
+		return
+	}
+
+	r.Created = append(r.Created, other.Created...)
+	r.Updated = append(r.Updated, other.Updated...)
+	r.Paused = append(r.Paused, other.Paused...)
+	r.Unpaused = append(r.Unpaused, other.Unpaused...)
+	r.Deleted = append(r.Deleted, other.Deleted...)
+	r.Skipped = append(r.Skipped, other.Skipped...)
+	r.Failed = append(r.Failed, other.Failed...)
+}
+
+// SyncPlan is the full diff between the console subscriptions and the schedules, as computed by a sync
+// before applying any mutation
+type SyncPlan struct {
+	CreatedAt time.Time       `json:"createdAt"`
+	Chains    []ChainSyncPlan `json:"chains"`
+	// Failed are the executors whose subscriptions could not be fetched
+	Failed []SyncReportEntry `json:"failed,omitempty"`
+}
+
+// Mutations returns the mutations of every chain of the plan
+func (p *SyncPlan) Mutations() []ScheduleMutation {
+	mutations := make([]ScheduleMutation, 0)
+	for _, chain := range p.Chains {
+		mutations = append(mutations, chain.Mutations...)
+	}
+	return mutations
+}
+
+// Report returns the skipped and failed entries of the plan
+func (p *SyncPlan) Report() *SyncReport {
+	report := NewSyncReport(p.CreatedAt)
+	report.Failed = append(report.Failed, p.Failed...)
+	for _, chain := range p.Chains {
+		report.Merge(chain.Report)
+	}
+	return report
+}
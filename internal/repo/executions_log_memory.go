@@ -0,0 +1,133 @@
+package repo
+
+import (
+	"bytes"
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/Brahma-fi/brahma-builder/internal/entity"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/google/uuid"
+)
+
+// MemoryExecutionsLogRepo keeps executions in memory, for tests and local runs without a database
+type MemoryExecutionsLogRepo struct {
+	mu   sync.RWMutex
+	logs map[uuid.UUID][]entity.Log
+	ids  map[uuid.UUID]struct{}
+}
+
+func NewMemoryExecutionsLogRepo() *MemoryExecutionsLogRepo {
+	return &MemoryExecutionsLogRepo{
+		logs: make(map[uuid.UUID][]entity.Log),
+		ids:  make(map[uuid.UUID]struct{}),
+	}
+}
+
+// LatestBySubID returns the latest execution of subID which recorded strategy state
+func (r *MemoryExecutionsLogRepo) LatestBySubID(_ context.Context, subID uuid.UUID) (*entity.Log, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var latest *entity.Log
+	for i, log := range r.logs[subID] {
+		if log.Metadata == nil {
+			continue
+		}
+		if latest == nil || !log.CreatedAt.Before(latest.CreatedAt) {
+			latest = &r.logs[subID][i]
+		}
+	}
+
+	if latest == nil {
+		return nil, entity.ErrExecutionLogNotFound
+	}
+
+	log := *latest
+	return &log, nil
+}
+
+// Insert stores log, a log with an already stored ID is ignored
+func (r *MemoryExecutionsLogRepo) Insert(_ context.Context, log *entity.Log) error {
+	metadata, err := jsonbValue(log.Metadata)
+	if err != nil {
+		return err
+	}
+
+	stored := *log
+	// empty metadata is stored as NULL, like the postgres repo does
+	if metadata == nil {
+		stored.Metadata = nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.ids[log.ID]; ok {
+		return nil
+	}
+
+	r.ids[log.ID] = struct{}{}
+	r.logs[log.SubscriptionID] = append(r.logs[log.SubscriptionID], stored)
+	return nil
+}
+
+// ListBySubAccount pages through the executions of subAccount, newest first, starting after the cursor
+func (r *MemoryExecutionsLogRepo) ListBySubAccount(
+	_ context.Context,
+	subAccount common.Address,
+	after *entity.LogCursor,
+	limit int,
+) (*entity.LogPage, error) {
+	return r.list(func(log entity.Log) bool {
+		return log.SubAccountAddress == subAccount.Hex()
+	}, after, limit), nil
+}
+
+// ListByChain pages through the executions on chainID, newest first, starting after the cursor
+func (r *MemoryExecutionsLogRepo) ListByChain(
+	_ context.Context,
+	chainID int64,
+	after *entity.LogCursor,
+	limit int,
+) (*entity.LogPage, error) {
+	return r.list(func(log entity.Log) bool {
+		return log.ChainID == chainID
+	}, after, limit), nil
+}
+
+func (r *MemoryExecutionsLogRepo) list(match func(entity.Log) bool, after *entity.LogCursor, limit int) *entity.LogPage {
+	r.mu.RLock()
+	logs := make([]entity.Log, 0)
+	for _, subLogs := range r.logs {
+		for _, log := range subLogs {
+			if match(log) && (after == nil || logBefore(log, *after)) {
+				logs = append(logs, log)
+			}
+		}
+	}
+	r.mu.RUnlock()
+
+	slices.SortFunc(logs, func(a, b entity.Log) int {
+		if c := b.CreatedAt.Compare(a.CreatedAt); c != 0 {
+			return c
+		}
+		return bytes.Compare(b.ID[:], a.ID[:])
+	})
+
+	limit = logPageSize(limit)
+	if len(logs) > limit+1 {
+		logs = logs[:limit+1]
+	}
+
+	return newLogPage(logs, limit)
+}
+
+// logBefore orders logs like the postgres repo, by creation time then ID
+func logBefore(log entity.Log, cursor entity.LogCursor) bool {
+	if !log.CreatedAt.Equal(cursor.CreatedAt) {
+		return log.CreatedAt.Before(cursor.CreatedAt)
+	}
+	return bytes.Compare(log.ID[:], cursor.ID[:]) < 0
+}
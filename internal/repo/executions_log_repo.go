@@ -0,0 +1,210 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/Brahma-fi/brahma-builder/internal/entity"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/google/uuid"
+)
+
+const (
+	_executionLogColumns = "id, sub_id, chain_id, metadata, subaccount_address, message, output_txn, created_at"
+	_defaultLogPageSize  = 50
+	_maxLogPageSize      = 500
+)
+
+// ExecutionsLogRepo keeps the executions of subscriptions in the executions_log table
+type ExecutionsLogRepo struct {
+	db *sql.DB
+}
+
+func NewExecutionsLogRepo(db *sql.DB) *ExecutionsLogRepo {
+	return &ExecutionsLogRepo{db: db}
+}
+
+// LatestBySubID returns the latest execution of subID which recorded strategy state
+func (r *ExecutionsLogRepo) LatestBySubID(ctx context.Context, subID uuid.UUID) (*entity.Log, error) {
+	row := r.db.QueryRowContext(
+		ctx,
+		"SELECT "+_executionLogColumns+" FROM executions_log"+
+			" WHERE sub_id = $1 AND metadata IS NOT NULL ORDER BY created_at DESC LIMIT 1",
+		subID,
+	)
+
+	log, err := scanLog(row)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return nil, entity.ErrExecutionLogNotFound
+	case err != nil:
+		return nil, fmt.Errorf("failed to get latest execution log: %w", err)
+	}
+
+	return log, nil
+}
+
+// ListBySubAccount pages through the executions of subAccount, newest first, starting after the cursor
+func (r *ExecutionsLogRepo) ListBySubAccount(
+	ctx context.Context,
+	subAccount common.Address,
+	after *entity.LogCursor,
+	limit int,
+) (*entity.LogPage, error) {
+	return r.list(ctx, "subaccount_address", subAccount.Hex(), after, limit)
+}
+
+// ListByChain pages through the executions on chainID, newest first, starting after the cursor
+func (r *ExecutionsLogRepo) ListByChain(
+	ctx context.Context,
+	chainID int64,
+	after *entity.LogCursor,
+	limit int,
+) (*entity.LogPage, error) {
+	return r.list(ctx, "chain_id", chainID, after, limit)
+}
+
+// list pages through the logs whose column equals value, column is never user input
+func (r *ExecutionsLogRepo) list(
+	ctx context.Context,
+	column string,
+	value any,
+	after *entity.LogCursor,
+	limit int,
+) (*entity.LogPage, error) {
+	limit = logPageSize(limit)
+	query := "SELECT " + _executionLogColumns + " FROM executions_log WHERE " + column + " = $1"
+	args := []any{value}
+	if after != nil {
+		query += " AND (created_at, id) < ($2, $3)"
+		args = append(args, after.CreatedAt, after.ID)
+	}
+	// one more row than the page tells whether a next page exists
+	query += fmt.Sprintf(" ORDER BY created_at DESC, id DESC LIMIT %d", limit+1)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list execution logs: %w", err)
+	}
+	defer rows.Close()
+
+	logs := make([]entity.Log, 0, limit)
+	for rows.Next() {
+		log, err := scanLog(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan execution log: %w", err)
+		}
+		logs = append(logs, *log)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list execution logs: %w", err)
+	}
+
+	return newLogPage(logs, limit), nil
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanLog(row rowScanner) (*entity.Log, error) {
+	log := &entity.Log{}
+	var metadata []byte
+	if err := row.Scan(
+		&log.ID,
+		&log.SubscriptionID,
+		&log.ChainID,
+		&metadata,
+		&log.SubAccountAddress,
+		&log.Message,
+		&log.OutputTxnHash,
+		&log.CreatedAt,
+	); err != nil {
+		return nil, err
+	}
+
+	// a NULL metadata is kept as a nil interface rather than a nil slice
+	if metadata != nil {
+		log.Metadata = metadata
+	}
+
+	return log, nil
+}
+
+func logPageSize(limit int) int {
+	switch {
+	case limit <= 0:
+		return _defaultLogPageSize
+	case limit > _maxLogPageSize:
+		return _maxLogPageSize
+	default:
+		return limit
+	}
+}
+
+// newLogPage builds the page of logs, which holds up to limit+1 logs ordered newest first
+func newLogPage(logs []entity.Log, limit int) *entity.LogPage {
+	if len(logs) <= limit {
+		return &entity.LogPage{Logs: logs}
+	}
+
+	logs = logs[:limit]
+	last := logs[len(logs)-1]
+	return &entity.LogPage{
+		Logs: logs,
+		Next: &entity.LogCursor{CreatedAt: last.CreatedAt, ID: last.ID},
+	}
+}
+
+// Insert stores log, a log with an already stored ID is ignored so retried inserts are harmless
+func (r *ExecutionsLogRepo) Insert(ctx context.Context, log *entity.Log) error {
+	metadata, err := jsonbValue(log.Metadata)
+	if err != nil {
+		return err
+	}
+
+	if _, err = r.db.ExecContext(
+		ctx,
+		"INSERT INTO executions_log ("+_executionLogColumns+") VALUES ($1, $2, $3, $4, $5, $6, $7, $8)"+
+			" ON CONFLICT (id) DO NOTHING",
+		log.ID,
+		log.SubscriptionID,
+		log.ChainID,
+		metadata,
+		log.SubAccountAddress,
+		log.Message,
+		log.OutputTxnHash,
+		log.CreatedAt,
+	); err != nil {
+		return fmt.Errorf("failed to insert execution log: %w", err)
+	}
+
+	return nil
+}
+
+// jsonbValue converts metadata to a value lib/pq writes as json rather than bytea
+func jsonbValue(metadata entity.Jsonb) (any, error) {
+	switch v := metadata.(type) {
+	case nil:
+		return nil, nil
+	case []byte:
+		if len(v) == 0 {
+			return nil, nil
+		}
+		return string(v), nil
+	case json.RawMessage:
+		return jsonbValue([]byte(v))
+	case string:
+		return v, nil
+	default:
+		raw, err := json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal execution log metadata: %w", err)
+		}
+		return string(raw), nil
+	}
+}
@@ -0,0 +1,233 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Brahma-fi/brahma-builder/internal/entity"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/google/uuid"
+	_ "github.com/lib/pq"
+)
+
+// _testStorageDSNEnv names the postgres database the executions log tests run against, each test in a schema
+// of its own. Without it only the in memory repo is tested.
+const _testStorageDSNEnv = "TEST_STORAGE_DSN"
+
+type executionsLogRepo interface {
+	LatestBySubID(ctx context.Context, subID uuid.UUID) (*entity.Log, error)
+	ListBySubAccount(ctx context.Context, subAccount common.Address, after *entity.LogCursor, limit int) (*entity.LogPage, error)
+	Insert(ctx context.Context, log *entity.Log) error
+}
+
+// executionsLogRepos returns a fresh repo of every implementation available
+func executionsLogRepos(t *testing.T) map[string]executionsLogRepo {
+	t.Helper()
+	repos := map[string]executionsLogRepo{"memory": NewMemoryExecutionsLogRepo()}
+	if dsn := os.Getenv(_testStorageDSNEnv); dsn != "" {
+		repos["postgres"] = NewExecutionsLogRepo(migratedDB(t, dsn))
+	}
+
+	return repos
+}
+
+// migratedDB opens dsn on a single connection scoped to a new schema holding the migrated tables
+func migratedDB(t *testing.T, dsn string) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+
+	ctx := context.Background()
+	schema := "executions_log_test_" + strings.ReplaceAll(uuid.NewString(), "-", "")
+	if _, err = db.ExecContext(ctx, fmt.Sprintf("CREATE SCHEMA %s; SET search_path TO %s", schema, schema)); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_, _ = db.ExecContext(ctx, fmt.Sprintf("DROP SCHEMA %s CASCADE", schema))
+		_ = db.Close()
+	})
+
+	migrations, err := filepath.Glob("../../migrations/*.up.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, migration := range migrations {
+		query, err := os.ReadFile(migration)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err = db.ExecContext(ctx, string(query)); err != nil {
+			t.Fatalf("failed to apply %s: %v", migration, err)
+		}
+	}
+
+	return db
+}
+
+func testLog(subID uuid.UUID, subAccount common.Address, createdAt time.Time, metadata entity.Jsonb) *entity.Log {
+	return &entity.Log{
+		ID:                uuid.New(),
+		SubscriptionID:    subID,
+		ChainID:           8453,
+		Metadata:          metadata,
+		SubAccountAddress: subAccount.Hex(),
+		CreatedAt:         createdAt,
+	}
+}
+
+func TestExecutionsLogInsertIsIdempotent(t *testing.T) {
+	ctx := context.Background()
+	subAccount := common.HexToAddress("0x5a")
+	createdAt := time.Now().UTC().Truncate(time.Microsecond)
+	for name, repo := range executionsLogRepos(t) {
+		t.Run(name, func(t *testing.T) {
+			log := testLog(uuid.New(), subAccount, createdAt, []byte(`{"step":1}`))
+			log.Message = "first attempt"
+			if err := repo.Insert(ctx, log); err != nil {
+				t.Fatal(err)
+			}
+
+			// a retried activity inserts the run again
+			retried := *log
+			retried.Message = "retried attempt"
+			if err := repo.Insert(ctx, &retried); err != nil {
+				t.Fatal(err)
+			}
+
+			page, err := repo.ListBySubAccount(ctx, subAccount, nil, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(page.Logs) != 1 {
+				t.Fatalf("got %d logs, want 1", len(page.Logs))
+			}
+
+			if page.Logs[0].ID != log.ID || page.Logs[0].Message != "first attempt" {
+				t.Fatalf("unexpected log %+v", page.Logs[0])
+			}
+		})
+	}
+}
+
+func TestExecutionsLogLatestBySubIDSkipsLogsWithoutState(t *testing.T) {
+	ctx := context.Background()
+	subAccount := common.HexToAddress("0x5a")
+	createdAt := time.Now().UTC().Truncate(time.Microsecond)
+	for name, repo := range executionsLogRepos(t) {
+		t.Run(name, func(t *testing.T) {
+			subID := uuid.New()
+			withState := testLog(subID, subAccount, createdAt, []byte(`{"step":1}`))
+			// skipped and failed runs are logged later, without state
+			withoutState := []*entity.Log{
+				testLog(subID, subAccount, createdAt.Add(time.Minute), nil),
+				testLog(subID, subAccount, createdAt.Add(2*time.Minute), []byte{}),
+			}
+			for _, log := range append([]*entity.Log{withState}, withoutState...) {
+				if err := repo.Insert(ctx, log); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			latest, err := repo.LatestBySubID(ctx, subID)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if latest.ID != withState.ID || !latest.CreatedAt.Equal(withState.CreatedAt) {
+				t.Fatalf("got log %s created at %s, want %s", latest.ID, latest.CreatedAt, withState.ID)
+			}
+
+			noState := uuid.New()
+			if err = repo.Insert(ctx, testLog(noState, subAccount, createdAt, nil)); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err = repo.LatestBySubID(ctx, noState); !errors.Is(err, entity.ErrExecutionLogNotFound) {
+				t.Fatalf("got %v, want %v", err, entity.ErrExecutionLogNotFound)
+			}
+		})
+	}
+}
+
+func TestExecutionsLogPagesAcrossEqualCreatedAt(t *testing.T) {
+	ctx := context.Background()
+	subAccount := common.HexToAddress("0x5a")
+	createdAt := time.Now().UTC().Truncate(time.Microsecond)
+	for name, repo := range executionsLogRepos(t) {
+		t.Run(name, func(t *testing.T) {
+			// five logs share a creation time, pages of two split them
+			inserted := make(map[uuid.UUID]bool)
+			for i := range 7 {
+				at := createdAt
+				if i >= 5 {
+					at = createdAt.Add(-time.Duration(i) * time.Second)
+				}
+				log := testLog(uuid.New(), subAccount, at, nil)
+				if err := repo.Insert(ctx, log); err != nil {
+					t.Fatal(err)
+				}
+				inserted[log.ID] = true
+			}
+
+			var (
+				logs  []entity.Log
+				after *entity.LogCursor
+			)
+			for pages := 0; ; pages++ {
+				if pages > len(inserted) {
+					t.Fatal("paging doesn't end")
+				}
+
+				page, err := repo.ListBySubAccount(ctx, subAccount, after, 2)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if len(page.Logs) > 2 {
+					t.Fatalf("got a page of %d logs, want at most 2", len(page.Logs))
+				}
+
+				logs = append(logs, page.Logs...)
+				if page.Next == nil {
+					break
+				}
+				after = page.Next
+			}
+
+			if len(logs) != len(inserted) {
+				t.Fatalf("got %d logs, want %d", len(logs), len(inserted))
+			}
+
+			seen := make(map[uuid.UUID]bool, len(logs))
+			for i, log := range logs {
+				if !inserted[log.ID] || seen[log.ID] {
+					t.Fatalf("log %s returned twice or never inserted", log.ID)
+				}
+				seen[log.ID] = true
+
+				if i == 0 {
+					continue
+				}
+
+				prev := logs[i-1]
+				if log.CreatedAt.After(prev.CreatedAt) ||
+					(log.CreatedAt.Equal(prev.CreatedAt) && strings.Compare(log.ID.String(), prev.ID.String()) > 0) {
+					t.Fatalf("log %s is out of order after %s", log.ID, prev.ID)
+				}
+			}
+		})
+	}
+}
@@ -5,11 +5,14 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"slices"
 	"strings"
 
 	"github.com/Brahma-fi/brahma-builder/internal/entity"
 	"github.com/ethereum/go-ethereum/common"
 	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/converter"
+	"golang.org/x/sync/errgroup"
 	"google.golang.org/protobuf/encoding/protojson"
 )
 
@@ -31,20 +34,37 @@ type pbMemo struct {
 }
 
 const (
-	_maxPageSize       = 100
-	KeyExecutionStatus = "ExecutionStatus"
+	_maxPageSize = 100
+	// _maxSubAccountsPerQuery bounds the IN clause of a visibility query, which Temporal limits in size
+	_maxSubAccountsPerQuery = 100
+	_maxConcurrentQueries   = 4
+	KeyExecutionStatus      = entity.SearchAttrKeyExecutionStatus
 )
 
+type scheduleClient interface {
+	List(ctx context.Context, options client.ScheduleListOptions) (client.ScheduleListIterator, error)
+}
+
 type ScheduleRepo struct {
-	client client.Client
+	client scheduleClient
 }
 
 func NewSchedulesRepo(client client.Client) *ScheduleRepo {
+	return NewSchedulesRepoWithClient(client.ScheduleClient())
+}
+
+// NewSchedulesRepoWithClient creates a repo listing schedules from any schedule client
+func NewSchedulesRepoWithClient(client scheduleClient) *ScheduleRepo {
 	return &ScheduleRepo{
 		client: client,
 	}
 }
 
+// List returns every schedule of the namespace
+func (s *ScheduleRepo) List(ctx context.Context) ([]entity.Schedule, error) {
+	return s.listSchedules(ctx, "")
+}
+
 func (s *ScheduleRepo) BySubAccountAddressChainIDAndStatus(
 	ctx context.Context,
 	subAccount common.Address,
@@ -69,21 +89,57 @@ func (s *ScheduleRepo) BySubAccountAddressesChainIDAndStatus(
 	return s.listSchedulesWithAdvancedQuery(ctx, subAccounts, chainID)
 }
 
+// listSchedulesWithAdvancedQuery lists the schedules of subAccounts on chainID, splitting
+// subAccounts into chunks queried concurrently and merging their results.
 func (s *ScheduleRepo) listSchedulesWithAdvancedQuery(
 	ctx context.Context,
 	subAccounts []common.Address,
 	chainID int64,
 ) ([]entity.Schedule, error) {
-	subAccStr := make([]string, len(subAccounts))
-	for i, subacc := range subAccounts {
-		subAccStr[i] = fmt.Sprintf("'%s'", subacc.Hex())
+	chunks := slices.Collect(slices.Chunk(subAccounts, _maxSubAccountsPerQuery))
+	results := make([][]entity.Schedule, len(chunks))
+
+	g, gCtx := errgroup.WithContext(ctx)
+	g.SetLimit(_maxConcurrentQueries)
+	for i, chunk := range chunks {
+		g.Go(func() error {
+			subAccStr := make([]string, len(chunk))
+			for j, subacc := range chunk {
+				subAccStr[j] = fmt.Sprintf("'%s'", subacc.Hex())
+			}
+
+			query := fmt.Sprintf("%s IN (%s) AND %s = %d",
+				entity.SearchAttrKeySubAccountAddress, strings.Join(subAccStr, ","),
+				entity.SearchAttrKeyChainID, chainID)
+
+			schedules, err := s.listSchedules(gCtx, query)
+			if err != nil {
+				return err
+			}
+
+			results[i] = schedules
+			return nil
+		})
 	}
 
-	query := fmt.Sprintf("%s IN (%s) AND %s = %d",
-		entity.SearchAttrKeySubAccountAddress, strings.Join(subAccStr, ","),
-		entity.SearchAttrKeyChainID, chainID)
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
 
-	return s.listSchedules(ctx, query)
+	// a sub account listed twice may land in two chunks, keep each schedule once
+	seen := make(map[string]struct{})
+	schedules := make([]entity.Schedule, 0)
+	for _, result := range results {
+		for _, schedule := range result {
+			if _, ok := seen[schedule.ScheduleID]; ok {
+				continue
+			}
+			seen[schedule.ScheduleID] = struct{}{}
+			schedules = append(schedules, schedule)
+		}
+	}
+
+	return schedules, nil
 }
 
 func (s *ScheduleRepo) filterSchedules(
@@ -132,7 +188,7 @@ func (s *ScheduleRepo) listSchedulesStandard(
 }
 
 func (s *ScheduleRepo) listSchedules(ctx context.Context, query string) ([]entity.Schedule, error) {
-	resp, err := s.client.ScheduleClient().List(ctx, client.ScheduleListOptions{
+	resp, err := s.client.List(ctx, client.ScheduleListOptions{
 		PageSize: _maxPageSize,
 		Query:    query,
 	})
@@ -179,13 +235,39 @@ func parseScheduleEntry(entry *client.ScheduleListEntry) (entity.Schedule, error
 		}
 	}
 
+	configHash, err := decodeKeywordSearchAttribute(entry, entity.SearchAttrKeyConfigHash)
+	if err != nil {
+		return entity.Schedule{}, fmt.Errorf("failed to decode config hash: %w", err)
+	}
+
 	return entity.Schedule{
 		Config:     memo,
 		ScheduleID: entry.ID,
 		CreatedAt:  entry.Spec.StartAt,
+		ConfigHash: configHash,
+		Paused:     entry.Paused,
+		Note:       entry.Note,
 	}, nil
 }
 
+func decodeKeywordSearchAttribute(entry *client.ScheduleListEntry, key string) (string, error) {
+	if entry.SearchAttributes == nil {
+		return "", nil
+	}
+
+	payload, ok := entry.SearchAttributes.GetIndexedFields()[key]
+	if !ok {
+		return "", nil
+	}
+
+	var value string
+	if err := converter.GetDefaultDataConverter().FromPayload(payload, &value); err != nil {
+		return "", err
+	}
+
+	return value, nil
+}
+
 func decodeMemoField(data string, target interface{}) error {
 	decodedData, err := base64.StdEncoding.DecodeString(data)
 	if err != nil {
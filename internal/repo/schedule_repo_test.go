@@ -0,0 +1,195 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"regexp"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/Brahma-fi/brahma-builder/internal/entity"
+	"github.com/ethereum/go-ethereum/common"
+	commonpb "go.temporal.io/api/common/v1"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/converter"
+)
+
+var (
+	_fakeQueryAddress = regexp.MustCompile(`'(0x[0-9a-fA-F]{40})'`)
+	_fakeQueryChainID = regexp.MustCompile(entity.SearchAttrKeyChainID + ` = (\d+)`)
+)
+
+// fakeScheduleClient answers the visibility queries of ScheduleRepo out of entries held in memory
+type fakeScheduleClient struct {
+	entries      []*client.ScheduleListEntry
+	params       map[string]entity.OrchestratorParams
+	bySubAccount map[common.Address][]*client.ScheduleListEntry
+
+	mu      sync.Mutex
+	queries []string
+}
+
+func newFakeScheduleClient(tb testing.TB, params []entity.OrchestratorParams) *fakeScheduleClient {
+	tb.Helper()
+	fake := &fakeScheduleClient{
+		params:       make(map[string]entity.OrchestratorParams, len(params)),
+		bySubAccount: make(map[common.Address][]*client.ScheduleListEntry),
+	}
+	dc := converter.GetDefaultDataConverter()
+	for _, p := range params {
+		paramsPayload, err := dc.ToPayload(p)
+		if err != nil {
+			tb.Fatal(err)
+		}
+
+		schedulePayload, err := dc.ToPayload(entity.ScheduledWorkflowConfig{ID: p.ID()})
+		if err != nil {
+			tb.Fatal(err)
+		}
+
+		entry := &client.ScheduleListEntry{
+			ID:   p.ID(),
+			Spec: &client.ScheduleSpec{},
+			Memo: &commonpb.Memo{Fields: map[string]*commonpb.Payload{
+				"params":   paramsPayload,
+				"schedule": schedulePayload,
+			}},
+		}
+		fake.params[p.ID()] = p
+		fake.entries = append(fake.entries, entry)
+		fake.bySubAccount[p.SubAccountAddress] = append(fake.bySubAccount[p.SubAccountAddress], entry)
+	}
+
+	return fake
+}
+
+func (f *fakeScheduleClient) List(_ context.Context, options client.ScheduleListOptions) (client.ScheduleListIterator, error) {
+	f.mu.Lock()
+	f.queries = append(f.queries, options.Query)
+	f.mu.Unlock()
+
+	if options.Query == "" {
+		return &fakeScheduleIterator{entries: slices.Clone(f.entries)}, nil
+	}
+
+	chainID := int64(-1)
+	if match := _fakeQueryChainID.FindStringSubmatch(options.Query); match != nil {
+		chainID, _ = strconv.ParseInt(match[1], 10, 64)
+	}
+
+	matched := make([]*client.ScheduleListEntry, 0)
+	for _, match := range _fakeQueryAddress.FindAllStringSubmatch(options.Query, -1) {
+		for _, entry := range f.bySubAccount[common.HexToAddress(match[1])] {
+			if f.params[entry.ID].ChainID == chainID {
+				matched = append(matched, entry)
+			}
+		}
+	}
+
+	return &fakeScheduleIterator{entries: matched}, nil
+}
+
+type fakeScheduleIterator struct {
+	entries []*client.ScheduleListEntry
+}
+
+func (i *fakeScheduleIterator) HasNext() bool {
+	return len(i.entries) != 0
+}
+
+func (i *fakeScheduleIterator) Next() (*client.ScheduleListEntry, error) {
+	entry := i.entries[0]
+	i.entries = i.entries[1:]
+	return entry, nil
+}
+
+// fakeSchedules returns n subscriptions on chainID, one per sub-account, plus one on another chain for every
+// tenth sub-account which a query for chainID must not return
+func fakeSchedules(n int, chainID int64) ([]entity.OrchestratorParams, []common.Address) {
+	params := make([]entity.OrchestratorParams, 0, n+n/10)
+	subAccounts := make([]common.Address, n)
+	for i := range n {
+		subAccounts[i] = common.BigToAddress(big.NewInt(int64(i + 1)))
+		p := entity.OrchestratorParams{
+			ExecutorAddress:   common.HexToAddress("0xe0"),
+			SubAccountAddress: subAccounts[i],
+			ChainID:           chainID,
+			Subscription:      entity.ClientSubscription{Id: fmt.Sprintf("sub-%d", i)},
+		}
+		params = append(params, p)
+
+		if i%10 == 0 {
+			p.ChainID = chainID + 1
+			params = append(params, p)
+		}
+	}
+
+	return params, subAccounts
+}
+
+func TestBySubAccountAddressesChainIDAndStatusMergesChunks(t *testing.T) {
+	const chainID = 8453
+	params, subAccounts := fakeSchedules(1050, chainID)
+	fake := newFakeScheduleClient(t, params)
+	repo := NewSchedulesRepoWithClient(fake)
+
+	// listing a sub-account twice must not return its schedule twice
+	queried := append(slices.Clone(subAccounts), subAccounts[:150]...)
+	schedules, err := repo.BySubAccountAddressesChainIDAndStatus(context.Background(), queried, chainID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(schedules) != len(subAccounts) {
+		t.Fatalf("got %d schedules, want %d", len(schedules), len(subAccounts))
+	}
+
+	seen := make(map[string]bool, len(schedules))
+	for _, schedule := range schedules {
+		if seen[schedule.ScheduleID] {
+			t.Fatalf("schedule %s returned twice", schedule.ScheduleID)
+		}
+		seen[schedule.ScheduleID] = true
+
+		if schedule.Config.Params.ChainID != chainID {
+			t.Fatalf("schedule %s is on chain %d", schedule.ScheduleID, schedule.Config.Params.ChainID)
+		}
+
+		if schedule.Config.Params.ID() != schedule.ScheduleID {
+			t.Fatalf("schedule %s decoded params of %s", schedule.ScheduleID, schedule.Config.Params.ID())
+		}
+	}
+
+	wantQueries := (len(queried) + _maxSubAccountsPerQuery - 1) / _maxSubAccountsPerQuery
+	if len(fake.queries) != wantQueries {
+		t.Fatalf("got %d queries, want %d", len(fake.queries), wantQueries)
+	}
+
+	for _, query := range fake.queries {
+		if n := len(_fakeQueryAddress.FindAllString(query, -1)); n > _maxSubAccountsPerQuery {
+			t.Fatalf("query holds %d sub-accounts, more than %d", n, _maxSubAccountsPerQuery)
+		}
+	}
+}
+
+func BenchmarkListSchedules(b *testing.B) {
+	const chainID = 8453
+	params, subAccounts := fakeSchedules(10_000, chainID)
+	repo := NewSchedulesRepoWithClient(newFakeScheduleClient(b, params))
+	ctx := context.Background()
+
+	b.ResetTimer()
+	for range b.N {
+		schedules, err := repo.BySubAccountAddressesChainIDAndStatus(ctx, subAccounts, chainID)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		if len(schedules) != len(subAccounts) {
+			b.Fatalf("got %d schedules, want %d", len(schedules), len(subAccounts))
+		}
+	}
+}
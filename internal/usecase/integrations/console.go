@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
 
 	"github.com/Brahma-fi/brahma-builder/internal/entity"
 	"github.com/Brahma-fi/brahma-builder/pkg/log"
@@ -12,9 +13,7 @@ import (
 	"github.com/go-resty/resty/v2"
 )
 
-const (
-	CancelledStatus = 4
-)
+const _subscriptionsPageSize = 500
 
 type ConsoleClient struct {
 	client *resty.Client
@@ -59,7 +58,7 @@ func (c *ConsoleClient) ActiveSubscriptions(
 
 	var activeSubscriptions []entity.ClientSubscription
 	for _, subscription := range subscriptions {
-		if subscription.Status != CancelledStatus {
+		if subscription.Status.ScheduleAction() != entity.ScheduleActionDelete {
 			activeSubscriptions = append(activeSubscriptions, subscription)
 		}
 	}
@@ -67,18 +66,83 @@ func (c *ConsoleClient) ActiveSubscriptions(
 	return activeSubscriptions, nil
 }
 
+// Subscriptions fetches every subscription of registryID, following the console's cursor page by page
 func (c *ConsoleClient) Subscriptions(ctx context.Context, registryID string) ([]entity.ClientSubscription, error) {
+	subscriptions := make([]entity.ClientSubscription, 0)
+	seenSubscriptions := make(map[string]struct{})
+	seenCursors := make(map[string]struct{})
+	cursor := ""
+	for {
+		page, err := c.SubscriptionsPage(ctx, registryID, cursor)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, subscription := range page.Data {
+			// a subscription created while paging may shift a page and be returned twice
+			if _, ok := seenSubscriptions[subscription.Id]; ok {
+				continue
+			}
+			seenSubscriptions[subscription.Id] = struct{}{}
+			subscriptions = append(subscriptions, subscription)
+		}
+
+		if page.NextCursor == "" {
+			return subscriptions, nil
+		}
+
+		if _, ok := seenCursors[page.NextCursor]; ok {
+			return nil, fmt.Errorf("subscriptions cursor %s repeated", page.NextCursor)
+		}
+		seenCursors[page.NextCursor] = struct{}{}
+		cursor = page.NextCursor
+	}
+}
+
+// SubscriptionsPage fetches a single page of subscriptions of registryID, starting at cursor
+func (c *ConsoleClient) SubscriptionsPage(
+	ctx context.Context,
+	registryID string,
+	cursor string,
+) (*entity.GetClientSubscriptionsResp, error) {
 	result := &entity.GetClientSubscriptionsResp{}
-	_, err := c.client.R().
+	req := c.client.R().
 		SetContext(ctx).
 		SetResult(result).
-		Get(fmt.Sprintf("/v1/automations/executor/%s/subscriptions", registryID))
+		SetQueryParam("limit", strconv.Itoa(_subscriptionsPageSize))
+	if cursor != "" {
+		req.SetQueryParam("cursor", cursor)
+	}
 
-	if err != nil {
+	resp, err := req.Get(fmt.Sprintf("/v1/automations/executor/%s/subscriptions", registryID))
+	switch {
+	case err != nil:
 		return nil, fmt.Errorf("failed to get executor subscriptions: %w", err)
+	case resp.StatusCode() != http.StatusOK:
+		return nil, fmt.Errorf("failed to get executor subscriptions: %d", resp.StatusCode())
 	}
 
-	return result.Data, nil
+	return result, nil
+}
+
+func (c *ConsoleClient) TaskStatus(ctx context.Context, taskID string) (*entity.TaskStatus, error) {
+	result := &entity.GetTaskStatusResp{}
+	resp, err := c.client.R().
+		SetContext(ctx).
+		SetResult(result).
+		Get(fmt.Sprintf("/v1/automations/tasks/status/%s", taskID))
+	switch {
+	case err != nil:
+		return nil, fmt.Errorf("failed to get task status: %w", err)
+	case resp.StatusCode() == http.StatusNotFound:
+		return nil, fmt.Errorf("task not found: %s", taskID)
+	case resp.StatusCode() != http.StatusOK:
+		return nil, fmt.Errorf("failed to get task status: %d", resp.StatusCode())
+	case result.Error != "":
+		return nil, fmt.Errorf("failed to get task status: %s", result.Error)
+	}
+
+	return &result.Data, nil
 }
 
 func (c *ConsoleClient) Execute(ctx context.Context, req *entity.ExecuteTaskReq) (*entity.ExecuteTaskResp, error) {
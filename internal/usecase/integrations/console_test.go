@@ -0,0 +1,154 @@
+package integrations
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/Brahma-fi/brahma-builder/internal/entity"
+)
+
+const _registryID = "7c2b9e4d-1f3a-4b8c-a6d5-0e9f8a7b6c5d"
+
+// The fixtures under testdata hold console responses in the shape the entity types decode:
+// subscriptions.json is an unpaginated subscriptions response, the shape the client read before it
+// requested pages, task_status.json a task status response.
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestSubscriptionsWithoutPaging(t *testing.T) {
+	fixture := readFixture(t, "subscriptions.json")
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path != "/v1/automations/executor/"+_registryID+"/subscriptions" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		// a console ignoring limit and cursor returns every subscription without a nextCursor
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(fixture)
+	}))
+	defer server.Close()
+
+	subscriptions, err := NewConsoleClient(server.URL).Subscriptions(context.Background(), _registryID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if requests != 1 {
+		t.Fatalf("got %d requests, want 1", requests)
+	}
+
+	if len(subscriptions) != 2 {
+		t.Fatalf("got %d subscriptions, want 2", len(subscriptions))
+	}
+
+	active := subscriptions[0]
+	if active.Status != entity.SubscriptionStatusActive || active.ChainId != 8453 || active.Duration != 2592000 {
+		t.Fatalf("unexpected subscription %+v", active)
+	}
+
+	if _, ok := active.ExpiresAt(); !ok {
+		t.Fatal("subscription with a duration must expire")
+	}
+
+	if subscriptions[1].Status != entity.SubscriptionStatusCancelled {
+		t.Fatalf("got status %s, want cancelled", subscriptions[1].Status)
+	}
+}
+
+func TestSubscriptionsFollowsCursor(t *testing.T) {
+	page := &entity.GetClientSubscriptionsResp{}
+	if err := json.Unmarshal(readFixture(t, "subscriptions.json"), page); err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if limit := r.URL.Query().Get("limit"); limit != strconv.Itoa(_subscriptionsPageSize) {
+			t.Errorf("got limit %q", limit)
+		}
+
+		// every page holds one subscription, the cursor being the index of the next one
+		i := 0
+		if cursor := r.URL.Query().Get("cursor"); cursor != "" {
+			i, _ = strconv.Atoi(cursor)
+		}
+
+		resp := entity.GetClientSubscriptionsResp{Data: page.Data[i : i+1]}
+		if i+1 < len(page.Data) {
+			resp.NextCursor = strconv.Itoa(i + 1)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	subscriptions, err := NewConsoleClient(server.URL).Subscriptions(context.Background(), _registryID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(subscriptions) != len(page.Data) {
+		t.Fatalf("got %d subscriptions, want %d", len(subscriptions), len(page.Data))
+	}
+
+	for i, subscription := range subscriptions {
+		if subscription.Id != page.Data[i].Id {
+			t.Fatalf("subscription %d is %s, want %s", i, subscription.Id, page.Data[i].Id)
+		}
+	}
+}
+
+func TestSubscriptionsRepeatedCursor(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(entity.GetClientSubscriptionsResp{NextCursor: "same"})
+	}))
+	defer server.Close()
+
+	if _, err := NewConsoleClient(server.URL).Subscriptions(context.Background(), _registryID); err == nil {
+		t.Fatal("expected an error on a repeated cursor")
+	}
+}
+
+func TestTaskStatus(t *testing.T) {
+	fixture := readFixture(t, "task_status.json")
+	const taskID = "b7e4c1d2-9a8f-4e3b-8c7d-6a5f4e3d2c1b"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/automations/tasks/status/"+taskID {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(fixture)
+	}))
+	defer server.Close()
+
+	console := NewConsoleClient(server.URL)
+	status, err := console.TaskStatus(context.Background(), taskID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if status.TaskID != taskID || status.Status != entity.TaskStateSuccessful || !status.Status.IsTerminal() {
+		t.Fatalf("unexpected task status %+v", status)
+	}
+
+	if status.TxHash == "" {
+		t.Fatal("expected the task's tx hash")
+	}
+
+	if _, err = console.TaskStatus(context.Background(), "unknown"); err == nil {
+		t.Fatal("expected an error for an unknown task")
+	}
+}
@@ -54,6 +54,25 @@ func (c *MorphoClient) Vaults(
 	return query.ToVaultInfo(), nil
 }
 
+// AssetPriceUSD returns the USD price Morpho's API quotes for asset on chainID
+func (c *MorphoClient) AssetPriceUSD(ctx context.Context, asset common.Address, chainID int64) (float64, error) {
+	var query entity.AssetPriceQuery
+	variables := map[string]interface{}{
+		"address": graphql.String(asset.Hex()),
+		"chainID": graphql.Int(chainID),
+	}
+
+	if err := c.client.Query(ctx, &query, variables); err != nil {
+		return 0, err
+	}
+
+	if query.AssetByAddress.PriceUsd == nil {
+		return 0, fmt.Errorf("no usd price for asset %s", asset.Hex())
+	}
+
+	return float64(*query.AssetByAddress.PriceUsd), nil
+}
+
 func (c *MorphoClient) User(ctx context.Context, address common.Address) ([]entity.UserInfo, error) {
 	var query entity.UserQuery
 	variables := map[string]interface{}{
@@ -134,6 +153,19 @@ func (c *MorphoClient) RedeemMax(
 	return vaultAbi.Pack("redeem", shares, depositor, depositor)
 }
 
+// Withdraw returns the call data withdrawing assets of depositor's position in a vault to depositor
+func (c *MorphoClient) Withdraw(
+	depositor common.Address,
+	assets *big.Int,
+) ([]byte, error) {
+	vaultAbi, err := abi.JSON(strings.NewReader(metamorpho.MorphoMetaData.ABI))
+	if err != nil {
+		return nil, err
+	}
+
+	return vaultAbi.Pack("withdraw", assets, depositor, depositor)
+}
+
 func (c *MorphoClient) Deposit(
 	depositor common.Address,
 	amt *big.Int,
@@ -0,0 +1,278 @@
+package oracle
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/Brahma-fi/brahma-builder/internal/entity"
+	utils "github.com/Brahma-fi/brahma-builder/pkg/utils/abis/erc20"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	_defaultMaxStaleness = 25 * time.Hour
+	_defaultMaxDeviation = 0.1
+	// _aggregatorV3ABI is the subset of AggregatorV3Interface the oracle reads
+	_aggregatorV3ABI = `[
+		{"inputs":[],"name":"decimals","outputs":[{"internalType":"uint8","name":"","type":"uint8"}],"stateMutability":"view","type":"function"},
+		{"inputs":[],"name":"latestRoundData","outputs":[{"internalType":"uint80","name":"roundId","type":"uint80"},{"internalType":"int256","name":"answer","type":"int256"},{"internalType":"uint256","name":"startedAt","type":"uint256"},{"internalType":"uint256","name":"updatedAt","type":"uint256"},{"internalType":"uint80","name":"answeredInRound","type":"uint80"}],"stateMutability":"view","type":"function"},
+		{"inputs":[{"internalType":"uint80","name":"_roundId","type":"uint80"}],"name":"getRoundData","outputs":[{"internalType":"uint80","name":"roundId","type":"uint80"},{"internalType":"int256","name":"answer","type":"int256"},{"internalType":"uint256","name":"startedAt","type":"uint256"},{"internalType":"uint256","name":"updatedAt","type":"uint256"},{"internalType":"uint80","name":"answeredInRound","type":"uint80"}],"stateMutability":"view","type":"function"}
+	]`
+)
+
+var _aggregatorRoundMask = new(big.Int).SetUint64(math.MaxUint64)
+
+// Price is a token's USD price as reported by its feed
+type Price struct {
+	// Answer is the USD price scaled by 10^Decimals
+	Answer    *big.Int
+	Decimals  uint8
+	UpdatedAt time.Time
+}
+
+type round struct {
+	RoundId         *big.Int
+	Answer          *big.Int
+	StartedAt       *big.Int
+	UpdatedAt       *big.Int
+	AnsweredInRound *big.Int
+}
+
+type feed struct {
+	contract      *bind.BoundContract
+	caller        bind.ContractCaller
+	token         common.Address
+	tokenDecimals uint8
+	maxStaleness  time.Duration
+	maxDeviation  float64
+}
+
+// Chainlink prices tokens through Chainlink style USD aggregators
+type Chainlink struct {
+	feeds map[int64]map[common.Address]*feed
+	now   func() time.Time
+}
+
+// NewChainlinkFromRPC creates the oracle of feeds, reading each chain through its retryable clients
+func NewChainlinkFromRPC(rpc rpcFactory, feeds entity.PriceFeedConfigs) (*Chainlink, error) {
+	callers := make(map[int64]bind.ContractCaller)
+	for _, f := range feeds {
+		if _, ok := callers[f.ChainID]; ok {
+			continue
+		}
+
+		client, err := rpc.RetryableClient(f.ChainID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get chain %d client: %w", f.ChainID, err)
+		}
+		callers[f.ChainID] = client
+	}
+
+	return NewChainlink(callers, feeds)
+}
+
+// NewChainlink creates the oracle of feeds, each read through the caller of its chain
+func NewChainlink(callers map[int64]bind.ContractCaller, feeds entity.PriceFeedConfigs) (*Chainlink, error) {
+	aggregatorABI, err := abi.JSON(strings.NewReader(_aggregatorV3ABI))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse aggregator ABI: %w", err)
+	}
+
+	c := &Chainlink{feeds: make(map[int64]map[common.Address]*feed), now: time.Now}
+	for _, f := range feeds {
+		caller, ok := callers[f.ChainID]
+		if !ok {
+			return nil, fmt.Errorf("no caller for chain %d", f.ChainID)
+		}
+
+		maxStaleness := _defaultMaxStaleness
+		if f.MaxStaleness != "" {
+			if maxStaleness, err = time.ParseDuration(f.MaxStaleness); err != nil {
+				return nil, fmt.Errorf("invalid max staleness of %s feed: %w", f.Token, err)
+			}
+		}
+
+		maxDeviation := f.MaxDeviation
+		if maxDeviation == 0 {
+			maxDeviation = _defaultMaxDeviation
+		}
+
+		if c.feeds[f.ChainID] == nil {
+			c.feeds[f.ChainID] = make(map[common.Address]*feed)
+		}
+		token := common.HexToAddress(f.Token)
+		c.feeds[f.ChainID][token] = &feed{
+			contract:      bind.NewBoundContract(common.HexToAddress(f.Feed), aggregatorABI, caller, nil, nil),
+			caller:        caller,
+			token:         token,
+			tokenDecimals: f.TokenDecimals,
+			maxStaleness:  maxStaleness,
+			maxDeviation:  maxDeviation,
+		}
+	}
+
+	return c, nil
+}
+
+// Price returns the latest price of token on chainID, once checked for staleness and deviation from the
+// previous round.
+func (c *Chainlink) Price(ctx context.Context, chainID int64, token common.Address) (*Price, error) {
+	f, err := c.feed(chainID, token)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := &bind.CallOpts{Context: ctx}
+	latest := &round{}
+	if err = call(f.contract, opts, latest, "latestRoundData"); err != nil {
+		return nil, fmt.Errorf("failed to get latest round of %s: %w", token.Hex(), err)
+	}
+
+	if latest.Answer.Sign() <= 0 || latest.AnsweredInRound.Cmp(latest.RoundId) < 0 {
+		return nil, fmt.Errorf("%w: %s answered %s in round %s", ErrInvalidPrice, token.Hex(), latest.Answer, latest.RoundId)
+	}
+
+	updatedAt := time.Unix(latest.UpdatedAt.Int64(), 0)
+	if age := c.now().Sub(updatedAt); age > f.maxStaleness {
+		return nil, fmt.Errorf("%w: %s updated %s ago", ErrStalePrice, token.Hex(), age.Round(time.Second))
+	}
+
+	if err = checkDeviation(f, opts, latest); err != nil {
+		return nil, fmt.Errorf("%s: %w", token.Hex(), err)
+	}
+
+	var decimals uint8
+	if err = call(f.contract, opts, &decimals, "decimals"); err != nil {
+		return nil, fmt.Errorf("failed to get feed decimals of %s: %w", token.Hex(), err)
+	}
+
+	return &Price{Answer: latest.Answer, Decimals: decimals, UpdatedAt: updatedAt}, nil
+}
+
+// ConvertUSDToToken returns the amount of token, in its base units, worth amtUSD. The amount is rounded
+// up so fees are never undercharged.
+func (c *Chainlink) ConvertUSDToToken(
+	ctx context.Context,
+	chainID int64,
+	amtUSD float64,
+	tokenAddress common.Address,
+) (*big.Int, error) {
+	price, err := c.Price(ctx, chainID, tokenAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	tokenDecimals, err := c.tokenDecimals(ctx, chainID, tokenAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	return USDToToken(amtUSD, price, tokenDecimals)
+}
+
+// USDToToken converts amtUSD to token base units at price, rounding up
+func USDToToken(amtUSD float64, price *Price, tokenDecimals uint8) (*big.Int, error) {
+	usd := new(big.Rat)
+	if amtUSD < 0 || usd.SetFloat64(amtUSD) == nil {
+		return nil, fmt.Errorf("invalid usd amount %f", amtUSD)
+	}
+
+	// amount = usd * 10^tokenDecimals * 10^priceDecimals / answer
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(tokenDecimals)+int64(price.Decimals)), nil)
+	amount := usd.Mul(usd, new(big.Rat).SetInt(scale))
+	amount.Quo(amount, new(big.Rat).SetInt(price.Answer))
+
+	out, rem := new(big.Int).QuoRem(amount.Num(), amount.Denom(), new(big.Int))
+	if rem.Sign() != 0 {
+		out.Add(out, big.NewInt(1))
+	}
+
+	return out, nil
+}
+
+func (c *Chainlink) feed(chainID int64, token common.Address) (*feed, error) {
+	f, ok := c.feeds[chainID][token]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s on chain %d", ErrFeedNotConfigured, token.Hex(), chainID)
+	}
+
+	return f, nil
+}
+
+func (c *Chainlink) tokenDecimals(ctx context.Context, chainID int64, token common.Address) (uint8, error) {
+	f, err := c.feed(chainID, token)
+	if err != nil {
+		return 0, err
+	}
+
+	if f.tokenDecimals != 0 {
+		return f.tokenDecimals, nil
+	}
+
+	erc20, err := utils.NewErc20Caller(token, f.caller)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create token caller: %w", err)
+	}
+
+	decimals, err := erc20.Decimals(&bind.CallOpts{Context: ctx})
+	if err != nil {
+		return 0, fmt.Errorf("failed to get decimals of %s: %w", token.Hex(), err)
+	}
+
+	return decimals, nil
+}
+
+// checkDeviation compares the latest round with the previous one of the same aggregator phase
+func checkDeviation(f *feed, opts *bind.CallOpts, latest *round) error {
+	// the low 64 bits of a round ID count the rounds of its phase, the first one has no previous round
+	if new(big.Int).And(latest.RoundId, _aggregatorRoundMask).Cmp(big.NewInt(1)) <= 0 {
+		return nil
+	}
+
+	prev := &round{}
+	if err := call(f.contract, opts, prev, "getRoundData", new(big.Int).Sub(latest.RoundId, big.NewInt(1))); err != nil {
+		return fmt.Errorf("failed to get previous round: %w", err)
+	}
+
+	if prev.Answer.Sign() <= 0 {
+		return nil
+	}
+
+	delta := new(big.Float).SetInt(new(big.Int).Abs(new(big.Int).Sub(latest.Answer, prev.Answer)))
+	deviation, _ := delta.Quo(delta, new(big.Float).SetInt(prev.Answer)).Float64()
+	if deviation > f.maxDeviation {
+		return fmt.Errorf("%w: %.4f from %s to %s", ErrPriceDeviation, deviation, prev.Answer, latest.Answer)
+	}
+
+	return nil
+}
+
+func call(contract *bind.BoundContract, opts *bind.CallOpts, out any, method string, params ...any) error {
+	var values []any
+	if err := contract.Call(opts, &values, method, params...); err != nil {
+		return err
+	}
+
+	switch v := out.(type) {
+	case *uint8:
+		*v = *abi.ConvertType(values[0], new(uint8)).(*uint8)
+	case *round:
+		*v = round{
+			RoundId:         *abi.ConvertType(values[0], new(*big.Int)).(**big.Int),
+			Answer:          *abi.ConvertType(values[1], new(*big.Int)).(**big.Int),
+			StartedAt:       *abi.ConvertType(values[2], new(*big.Int)).(**big.Int),
+			UpdatedAt:       *abi.ConvertType(values[3], new(*big.Int)).(**big.Int),
+			AnsweredInRound: *abi.ConvertType(values[4], new(*big.Int)).(**big.Int),
+		}
+	default:
+		return fmt.Errorf("unsupported output %T", out)
+	}
+
+	return nil
+}
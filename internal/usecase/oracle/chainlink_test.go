@@ -0,0 +1,277 @@
+package oracle
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/Brahma-fi/brahma-builder/internal/entity"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient/simulated"
+)
+
+const _testChainID = 1337
+
+var (
+	_testFeed  = common.HexToAddress("0xfeed")
+	_testToken = common.HexToAddress("0x70c0")
+	// _aggregatorCode answers decimals() with slot 0, latestRoundData() with the round whose ID is in slot 1
+	// and getRoundData(id) with the round stored from slot id<<8, its five fields in consecutive slots.
+	//
+	//	    PUSH1 0 CALLDATALOAD PUSH1 0xe0 SHR
+	//	    DUP1 PUSH4 decimals EQ PUSH1 dec JUMPI
+	//	    DUP1 PUSH4 latestRoundData EQ PUSH1 latest JUMPI
+	//	    DUP1 PUSH4 getRoundData EQ PUSH1 get JUMPI
+	//	    PUSH1 0 DUP1 REVERT
+	//	dec: JUMPDEST PUSH1 0 SLOAD PUSH1 0 MSTORE PUSH1 0x20 PUSH1 0 RETURN
+	//	latest: JUMPDEST PUSH1 1 SLOAD PUSH1 round JUMP
+	//	get: JUMPDEST PUSH1 4 CALLDATALOAD PUSH1 round JUMP
+	//	round: JUMPDEST PUSH1 8 SHL
+	//	    (DUP1 PUSH1 k ADD SLOAD PUSH1 k*32 MSTORE) for k in 0..4
+	//	    PUSH1 0xa0 PUSH1 0 RETURN
+	_aggregatorCode = hexutil.MustDecode("0x60003560e01c8063313ce567146028578063feaf968c1460345780639a6fc8f514603b57600080fd" +
+		"5b60005460005260206000f35b6001546042565b6004356042565b60081b80600001546000528060010154602052806002015460405280" +
+		"60030154606052806004015460805260a06000f3")
+)
+
+// testRound is a round stored in the simulated aggregator, answeredInRound defaults to the round's ID
+type testRound struct {
+	id              *big.Int
+	answer          *big.Int
+	updatedAt       time.Time
+	answeredInRound *big.Int
+}
+
+// roundID is the ID of the aggregatorRound-th round of phase
+func roundID(phase, aggregatorRound uint64) *big.Int {
+	id := new(big.Int).Lsh(new(big.Int).SetUint64(phase), 64)
+	return id.Or(id, new(big.Int).SetUint64(aggregatorRound))
+}
+
+// newTestChainlink deploys the aggregator holding rounds, the last of them being the latest, on a simulated chain
+func newTestChainlink(t *testing.T, now time.Time, feedDecimals uint8, rounds ...testRound) *Chainlink {
+	t.Helper()
+	storage := map[common.Hash]common.Hash{
+		common.BigToHash(big.NewInt(0)): common.BigToHash(big.NewInt(int64(feedDecimals))),
+		common.BigToHash(big.NewInt(1)): common.BigToHash(rounds[len(rounds)-1].id),
+	}
+	for _, r := range rounds {
+		answeredInRound := r.answeredInRound
+		if answeredInRound == nil {
+			answeredInRound = r.id
+		}
+
+		base := new(big.Int).Lsh(r.id, 8)
+		fields := []*big.Int{r.id, r.answer, big.NewInt(r.updatedAt.Unix()), big.NewInt(r.updatedAt.Unix()), answeredInRound}
+		for k, field := range fields {
+			storage[common.BigToHash(new(big.Int).Add(base, big.NewInt(int64(k))))] = common.BytesToHash(int256(field))
+		}
+	}
+
+	backend := simulated.NewBackend(types.GenesisAlloc{_testFeed: {Code: _aggregatorCode, Storage: storage, Balance: big.NewInt(0)}})
+	t.Cleanup(func() { _ = backend.Close() })
+
+	oracle, err := NewChainlink(
+		map[int64]bind.ContractCaller{_testChainID: backend.Client()},
+		entity.PriceFeedConfigs{{
+			ChainID:       _testChainID,
+			Token:         _testToken.Hex(),
+			Feed:          _testFeed.Hex(),
+			TokenDecimals: 6,
+		}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	oracle.now = func() time.Time { return now }
+
+	return oracle
+}
+
+// int256 is the 32 byte two's complement of v
+func int256(v *big.Int) []byte {
+	if v.Sign() >= 0 {
+		return common.BigToHash(v).Bytes()
+	}
+	return common.BigToHash(new(big.Int).Add(new(big.Int).Lsh(big.NewInt(1), 256), v)).Bytes()
+}
+
+func TestChainlinkPrice(t *testing.T) {
+	now := time.Unix(1_760_000_000, 0)
+	usd := func(dollars int64) *big.Int {
+		return new(big.Int).Mul(big.NewInt(dollars), big.NewInt(1e8))
+	}
+
+	tests := []struct {
+		name    string
+		rounds  []testRound
+		want    *big.Int
+		wantErr error
+	}{
+		{
+			name: "latest round",
+			rounds: []testRound{
+				{id: roundID(1, 9), answer: usd(100), updatedAt: now.Add(-2 * time.Hour)},
+				{id: roundID(1, 10), answer: usd(105), updatedAt: now.Add(-time.Hour)},
+			},
+			want: usd(105),
+		},
+		{
+			name: "stale round",
+			rounds: []testRound{
+				{id: roundID(1, 9), answer: usd(100), updatedAt: now.Add(-27 * time.Hour)},
+				{id: roundID(1, 10), answer: usd(100), updatedAt: now.Add(-26 * time.Hour)},
+			},
+			wantErr: ErrStalePrice,
+		},
+		{
+			name: "zero answer",
+			rounds: []testRound{
+				{id: roundID(1, 10), answer: big.NewInt(0), updatedAt: now},
+			},
+			wantErr: ErrInvalidPrice,
+		},
+		{
+			name: "negative answer",
+			rounds: []testRound{
+				{id: roundID(1, 10), answer: usd(-1), updatedAt: now},
+			},
+			wantErr: ErrInvalidPrice,
+		},
+		{
+			name: "answered in an earlier round",
+			rounds: []testRound{
+				{id: roundID(1, 10), answer: usd(100), updatedAt: now, answeredInRound: roundID(1, 9)},
+			},
+			wantErr: ErrInvalidPrice,
+		},
+		{
+			name: "deviates from previous round",
+			rounds: []testRound{
+				{id: roundID(1, 9), answer: usd(100), updatedAt: now.Add(-2 * time.Hour)},
+				{id: roundID(1, 10), answer: usd(120), updatedAt: now.Add(-time.Hour)},
+			},
+			wantErr: ErrPriceDeviation,
+		},
+		{
+			name: "previous round without answer",
+			rounds: []testRound{
+				{id: roundID(1, 10), answer: usd(120), updatedAt: now},
+			},
+			want: usd(120),
+		},
+		{
+			// the first round of a phase has no previous round, whatever its ID minus one answers
+			name: "first round of a phase",
+			rounds: []testRound{
+				{id: new(big.Int).Sub(roundID(2, 1), big.NewInt(1)), answer: usd(1), updatedAt: now.Add(-time.Hour)},
+				{id: roundID(2, 1), answer: usd(120), updatedAt: now},
+			},
+			want: usd(120),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oracle := newTestChainlink(t, now, 8, tt.rounds...)
+			price, err := oracle.Price(context.Background(), _testChainID, _testToken)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("got %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if price.Answer.Cmp(tt.want) != 0 || price.Decimals != 8 {
+				t.Fatalf("got %s with %d decimals, want %s with 8", price.Answer, price.Decimals, tt.want)
+			}
+		})
+	}
+}
+
+func TestChainlinkPriceOfUnknownFeed(t *testing.T) {
+	now := time.Unix(1_760_000_000, 0)
+	oracle := newTestChainlink(t, now, 8, testRound{id: roundID(1, 1), answer: big.NewInt(1e8), updatedAt: now})
+	if _, err := oracle.Price(context.Background(), _testChainID, common.HexToAddress("0xdead")); !errors.Is(err, ErrFeedNotConfigured) {
+		t.Fatalf("got %v, want %v", err, ErrFeedNotConfigured)
+	}
+}
+
+func TestChainlinkConvertUSDToTokenRoundsUp(t *testing.T) {
+	now := time.Unix(1_760_000_000, 0)
+	// a token worth $3, with 6 decimals
+	oracle := newTestChainlink(t, now, 8, testRound{id: roundID(1, 1), answer: big.NewInt(3e8), updatedAt: now})
+	amount, err := oracle.ConvertUSDToToken(context.Background(), _testChainID, 1, _testToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if amount.Cmp(big.NewInt(333_334)) != 0 {
+		t.Fatalf("got %s, want 333334", amount)
+	}
+}
+
+func TestUSDToToken(t *testing.T) {
+	tests := []struct {
+		name          string
+		amtUSD        float64
+		price         *Price
+		tokenDecimals uint8
+		want          *big.Int
+	}{
+		{
+			name:          "exact",
+			amtUSD:        2,
+			price:         &Price{Answer: big.NewInt(2e8), Decimals: 8},
+			tokenDecimals: 6,
+			want:          big.NewInt(1e6),
+		},
+		{
+			name:          "rounded up",
+			amtUSD:        1,
+			price:         &Price{Answer: big.NewInt(3e8), Decimals: 8},
+			tokenDecimals: 6,
+			want:          big.NewInt(333_334),
+		},
+		{
+			name:          "fraction of a base unit",
+			amtUSD:        0.0000001,
+			price:         &Price{Answer: big.NewInt(1e8), Decimals: 8},
+			tokenDecimals: 6,
+			want:          big.NewInt(1),
+		},
+		{
+			name:          "zero",
+			amtUSD:        0,
+			price:         &Price{Answer: big.NewInt(1e8), Decimals: 8},
+			tokenDecimals: 18,
+			want:          big.NewInt(0),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := USDToToken(tt.amtUSD, tt.price, tt.tokenDecimals)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got.Cmp(tt.want) != 0 {
+				t.Fatalf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := USDToToken(-1, &Price{Answer: big.NewInt(1e8), Decimals: 8}, 6); err == nil {
+		t.Fatal("expected an error for a negative amount")
+	}
+}
@@ -0,0 +1,252 @@
+package oracle
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/big"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/Brahma-fi/brahma-builder/internal/entity"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	_defaultCacheTTL            = time.Minute
+	_defaultCompositeDeviation  = 0.05
+	_defaultCompositeMinSources = 1
+)
+
+type priceKey struct {
+	chainID int64
+	token   common.Address
+}
+
+type cachedPrice struct {
+	price     *Price
+	expiresAt time.Time
+}
+
+type quote struct {
+	source string
+	price  *Price
+	usd    float64
+}
+
+// Composite prices tokens with the median of its sources, once quotes too far from it are rejected.
+// Rejecting an outlier takes three sources or more: the median of two sits halfway between them, so two
+// sources apart by more than twice the max deviation are both rejected. When too few quotes are kept no
+// price is returned rather than one which may be the outlier. The fallback source is only queried when no
+// other source quotes a price.
+type Composite struct {
+	sources      []Source
+	fallback     Source
+	decimals     decimalsReader
+	ttl          time.Duration
+	maxDeviation float64
+	minSources   int
+	now          func() time.Time
+
+	mu    sync.Mutex
+	cache map[priceKey]cachedPrice
+}
+
+func NewComposite(
+	cfg entity.OracleConfig,
+	decimals decimalsReader,
+	fallback Source,
+	sources ...Source,
+) (*Composite, error) {
+	c := &Composite{
+		sources:      sources,
+		fallback:     fallback,
+		decimals:     decimals,
+		ttl:          _defaultCacheTTL,
+		maxDeviation: cfg.MaxDeviation,
+		minSources:   cfg.MinSources,
+		now:          time.Now,
+		cache:        make(map[priceKey]cachedPrice),
+	}
+
+	if cfg.CacheTTL != "" {
+		ttl, err := time.ParseDuration(cfg.CacheTTL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid oracle cache ttl: %w", err)
+		}
+		c.ttl = ttl
+	}
+
+	if c.maxDeviation == 0 {
+		c.maxDeviation = _defaultCompositeDeviation
+	}
+
+	if c.minSources == 0 {
+		c.minSources = _defaultCompositeMinSources
+	}
+
+	if c.minSources > len(sources) {
+		return nil, fmt.Errorf("oracle needs %d sources, %d configured", c.minSources, len(sources))
+	}
+
+	return c, nil
+}
+
+// Price returns the median price of token on chainID, cached for the configured TTL
+func (c *Composite) Price(ctx context.Context, chainID int64, token common.Address) (*Price, error) {
+	key := priceKey{chainID: chainID, token: token}
+	c.mu.Lock()
+	cached, ok := c.cache[key]
+	c.mu.Unlock()
+	if ok && c.now().Before(cached.expiresAt) {
+		return cached.price, nil
+	}
+
+	price, err := c.aggregate(ctx, chainID, token)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.cache[key] = cachedPrice{price: price, expiresAt: c.now().Add(c.ttl)}
+	c.mu.Unlock()
+
+	return price, nil
+}
+
+// ConvertUSDToToken returns the amount of token, in its base units, worth amtUSD at the median price
+func (c *Composite) ConvertUSDToToken(
+	ctx context.Context,
+	chainID int64,
+	amtUSD float64,
+	tokenAddress common.Address,
+) (*big.Int, error) {
+	price, err := c.Price(ctx, chainID, tokenAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	tokenDecimals, err := c.decimals.Decimals(ctx, chainID, tokenAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	return USDToToken(amtUSD, price, tokenDecimals)
+}
+
+// ConvertTokenToUSD returns the USD value of amt, in token's base units, at the median price
+func (c *Composite) ConvertTokenToUSD(
+	ctx context.Context,
+	chainID int64,
+	amt *big.Int,
+	tokenAddress common.Address,
+) (float64, error) {
+	price, err := c.Price(ctx, chainID, tokenAddress)
+	if err != nil {
+		return 0, err
+	}
+
+	tokenDecimals, err := c.decimals.Decimals(ctx, chainID, tokenAddress)
+	if err != nil {
+		return 0, err
+	}
+
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(tokenDecimals)+int64(price.Decimals)), nil)
+	usd, _ := new(big.Rat).SetFrac(new(big.Int).Mul(amt, price.Answer), scale).Float64()
+	return usd, nil
+}
+
+func (c *Composite) aggregate(ctx context.Context, chainID int64, token common.Address) (*Price, error) {
+	quotes, sourceErrs := query(ctx, c.sources, chainID, token)
+	if len(quotes) == 0 && c.fallback != nil {
+		var fallbackErrs SourceErrors
+		if quotes, fallbackErrs = query(ctx, []Source{c.fallback}, chainID, token); len(quotes) != 0 {
+			return quotes[0].price, nil
+		}
+		sourceErrs = append(sourceErrs, fallbackErrs...)
+	}
+
+	switch {
+	case len(quotes) == 0 && sourceErrs.allStale():
+		return nil, fmt.Errorf("%w for %s on chain %d: %w", ErrAllSourcesStale, token.Hex(), chainID, sourceErrs)
+	case len(quotes) == 0:
+		return nil, fmt.Errorf("%w for %s on chain %d: %w", ErrNoPrice, token.Hex(), chainID, sourceErrs)
+	}
+
+	median := medianUSD(quotes)
+	kept := slices.DeleteFunc(slices.Clone(quotes), func(q quote) bool {
+		return math.Abs(q.usd-median)/median > c.maxDeviation
+	})
+	if len(kept) < c.minSources {
+		return nil, fmt.Errorf(
+			"%w for %s on chain %d: %d of %d quotes within %.4f of %f",
+			ErrNoConsensus, token.Hex(), chainID, len(kept), len(quotes), c.maxDeviation, median,
+		)
+	}
+
+	price, err := floatPrice(medianUSD(kept), oldestUpdate(kept))
+	if err != nil {
+		return nil, err
+	}
+
+	return price, nil
+}
+
+// query asks every source for the price of token concurrently
+func query(ctx context.Context, sources []Source, chainID int64, token common.Address) ([]quote, SourceErrors) {
+	quotes := make([]*quote, len(sources))
+	errs := make([]error, len(sources))
+	var wg sync.WaitGroup
+	for i, source := range sources {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			price, err := source.Price(ctx, chainID, token)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			quotes[i] = &quote{source: source.Name(), price: price, usd: price.Float()}
+		}()
+	}
+	wg.Wait()
+
+	out := make([]quote, 0, len(sources))
+	var sourceErrs SourceErrors
+	for i := range sources {
+		if errs[i] != nil {
+			sourceErrs = append(sourceErrs, SourceError{Source: sources[i].Name(), Err: errs[i]})
+			continue
+		}
+		out = append(out, *quotes[i])
+	}
+
+	return out, sourceErrs
+}
+
+func medianUSD(quotes []quote) float64 {
+	prices := make([]float64, len(quotes))
+	for i := range quotes {
+		prices[i] = quotes[i].usd
+	}
+	slices.Sort(prices)
+
+	mid := len(prices) / 2
+	if len(prices)%2 == 0 {
+		return (prices[mid-1] + prices[mid]) / 2
+	}
+
+	return prices[mid]
+}
+
+func oldestUpdate(quotes []quote) time.Time {
+	oldest := quotes[0].price.UpdatedAt
+	for _, q := range quotes[1:] {
+		if q.price.UpdatedAt.Before(oldest) {
+			oldest = q.price.UpdatedAt
+		}
+	}
+
+	return oldest
+}
@@ -0,0 +1,146 @@
+package oracle
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/Brahma-fi/brahma-builder/internal/entity"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// fakeSource quotes priceUSD for every token, or fails with err
+type fakeSource struct {
+	name     string
+	priceUSD float64
+	err      error
+	queries  int
+}
+
+func (f *fakeSource) Name() string {
+	return f.name
+}
+
+func (f *fakeSource) Price(_ context.Context, _ int64, _ common.Address) (*Price, error) {
+	f.queries++
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	return floatPrice(f.priceUSD, time.Unix(1_760_000_000, 0))
+}
+
+func TestCompositePrice(t *testing.T) {
+	stale := &fakeSource{name: "chainlink", err: ErrStalePrice}
+	tests := []struct {
+		name     string
+		cfg      entity.OracleConfig
+		sources  []Source
+		fallback Source
+		want     float64
+		wantErr  error
+	}{
+		{
+			name:    "median of agreeing sources",
+			sources: []Source{&fakeSource{name: "chainlink", priceUSD: 1}, &fakeSource{name: "morpho", priceUSD: 1.02}},
+			want:    1.01,
+		},
+		{
+			name: "outlier of three sources rejected",
+			sources: []Source{
+				&fakeSource{name: "chainlink", priceUSD: 1},
+				&fakeSource{name: "morpho", priceUSD: 1.01},
+				&fakeSource{name: "other", priceUSD: 2},
+			},
+			want: 1.005,
+		},
+		{
+			// the median of two sources is rejected with both, neither may be trusted over the other
+			name:     "two disagreeing sources",
+			sources:  []Source{&fakeSource{name: "chainlink", priceUSD: 1}, &fakeSource{name: "morpho", priceUSD: 2}},
+			fallback: &fakeSource{name: "static", priceUSD: 3},
+			wantErr:  ErrNoConsensus,
+		},
+		{
+			name:     "too few sources quoting",
+			cfg:      entity.OracleConfig{MinSources: 2},
+			sources:  []Source{stale, &fakeSource{name: "morpho", priceUSD: 2}},
+			fallback: &fakeSource{name: "static", priceUSD: 3},
+			wantErr:  ErrNoConsensus,
+		},
+		{
+			name:     "no source quotes",
+			sources:  []Source{stale, &fakeSource{name: "morpho", err: errors.New("unavailable")}},
+			fallback: &fakeSource{name: "static", priceUSD: 3},
+			want:     3,
+		},
+		{
+			name:     "every source stale",
+			sources:  []Source{stale},
+			fallback: &fakeSource{name: "static", err: ErrFeedNotConfigured},
+			wantErr:  ErrAllSourcesStale,
+		},
+		{
+			name:    "no price",
+			sources: []Source{&fakeSource{name: "morpho", err: errors.New("unavailable")}},
+			wantErr: ErrNoPrice,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oracle, err := NewComposite(tt.cfg, nil, tt.fallback, tt.sources...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			price, err := oracle.Price(context.Background(), 8453, common.HexToAddress("0x70c0"))
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("got %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := price.Float(); math.Abs(got-tt.want) > 1e-9 {
+				t.Fatalf("got %f, want %f", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompositePriceIsCached(t *testing.T) {
+	source := &fakeSource{name: "chainlink", priceUSD: 1}
+	oracle, err := NewComposite(entity.OracleConfig{CacheTTL: "1m"}, nil, nil, source)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Unix(1_760_000_000, 0)
+	oracle.now = func() time.Time { return now }
+	token := common.HexToAddress("0x70c0")
+	for range 2 {
+		if _, err = oracle.Price(context.Background(), 8453, token); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if source.queries != 1 {
+		t.Fatalf("got %d queries, want 1", source.queries)
+	}
+
+	now = now.Add(time.Minute)
+	if _, err = oracle.Price(context.Background(), 8453, token); err != nil {
+		t.Fatal(err)
+	}
+
+	if source.queries != 2 {
+		t.Fatalf("got %d queries once expired, want 2", source.queries)
+	}
+}
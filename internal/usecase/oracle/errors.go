@@ -0,0 +1,68 @@
+package oracle
+
+import (
+	"errors"
+	"strings"
+)
+
+var (
+	ErrFeedNotConfigured = errors.New("price feed not configured")
+	ErrInvalidPrice      = errors.New("invalid price")
+	ErrStalePrice        = errors.New("stale price")
+	ErrPriceDeviation    = errors.New("price deviates from previous round")
+	// ErrAllSourcesStale is returned when every source of a token only has stale prices
+	ErrAllSourcesStale = errors.New("every price source is stale")
+	ErrNoPrice         = errors.New("no price source available")
+	// ErrNoConsensus is returned when too few sources quote close enough to the median
+	ErrNoConsensus = errors.New("price sources disagree")
+)
+
+// SourceError is the failure of a single price source
+type SourceError struct {
+	Source string
+	Err    error
+}
+
+func (e SourceError) Error() string {
+	return e.Source + ": " + e.Err.Error()
+}
+
+func (e SourceError) Unwrap() error {
+	return e.Err
+}
+
+// SourceErrors are the failures of every source queried for a price
+type SourceErrors []SourceError
+
+func (e SourceErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i := range e {
+		msgs[i] = e[i].Error()
+	}
+
+	return strings.Join(msgs, "; ")
+}
+
+func (e SourceErrors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i := range e {
+		errs[i] = e[i]
+	}
+
+	return errs
+}
+
+// allStale is true when every source configured for the token failed with a stale price
+func (e SourceErrors) allStale() bool {
+	stale := false
+	for _, err := range e {
+		switch {
+		case errors.Is(err.Err, ErrStalePrice):
+			stale = true
+		case !errors.Is(err.Err, ErrFeedNotConfigured):
+			return false
+		}
+	}
+
+	return stale
+}
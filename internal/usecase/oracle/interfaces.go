@@ -0,0 +1,20 @@
+package oracle
+
+import (
+	"context"
+
+	"github.com/Brahma-fi/brahma-builder/pkg/rpc"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+type rpcFactory interface {
+	RetryableClient(chainID int64) (*rpc.Clients, error)
+}
+
+type assetPricer interface {
+	AssetPriceUSD(ctx context.Context, asset common.Address, chainID int64) (float64, error)
+}
+
+type decimalsReader interface {
+	Decimals(ctx context.Context, chainID int64, token common.Address) (uint8, error)
+}
@@ -0,0 +1,142 @@
+package oracle
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/Brahma-fi/brahma-builder/internal/entity"
+	utils "github.com/Brahma-fi/brahma-builder/pkg/utils/abis/erc20"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// _usdDecimals are the decimals of prices quoted as a float, such as Morpho's and static ones
+const _usdDecimals = 18
+
+// Source is a single source of USD prices
+type Source interface {
+	Name() string
+	Price(ctx context.Context, chainID int64, token common.Address) (*Price, error)
+}
+
+func (c *Chainlink) Name() string {
+	return "chainlink"
+}
+
+// Morpho prices assets with the USD prices of Morpho's API
+type Morpho struct {
+	client assetPricer
+	now    func() time.Time
+}
+
+func NewMorpho(client assetPricer) *Morpho {
+	return &Morpho{client: client, now: time.Now}
+}
+
+func (m *Morpho) Name() string {
+	return "morpho"
+}
+
+func (m *Morpho) Price(ctx context.Context, chainID int64, token common.Address) (*Price, error) {
+	priceUSD, err := m.client.AssetPriceUSD(ctx, token, chainID)
+	if err != nil {
+		return nil, err
+	}
+
+	// the API does not say when the price was updated, it is as fresh as the query
+	return floatPrice(priceUSD, m.now())
+}
+
+// Static prices tokens from configuration
+type Static struct {
+	prices map[int64]map[common.Address]float64
+	now    func() time.Time
+}
+
+func NewStatic(prices []entity.StaticPriceConfig) *Static {
+	s := &Static{prices: make(map[int64]map[common.Address]float64), now: time.Now}
+	for _, p := range prices {
+		if s.prices[p.ChainID] == nil {
+			s.prices[p.ChainID] = make(map[common.Address]float64)
+		}
+		s.prices[p.ChainID][common.HexToAddress(p.Token)] = p.PriceUSD
+	}
+
+	return s
+}
+
+func (s *Static) Name() string {
+	return "static"
+}
+
+func (s *Static) Price(_ context.Context, chainID int64, token common.Address) (*Price, error) {
+	priceUSD, ok := s.prices[chainID][token]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s on chain %d", ErrFeedNotConfigured, token.Hex(), chainID)
+	}
+
+	return floatPrice(priceUSD, s.now())
+}
+
+// TokenDecimals reads the decimals of ERC20 tokens, once per token
+type TokenDecimals struct {
+	callers  map[int64]bind.ContractCaller
+	mu       sync.RWMutex
+	decimals map[int64]map[common.Address]uint8
+}
+
+func NewTokenDecimals(callers map[int64]bind.ContractCaller) *TokenDecimals {
+	return &TokenDecimals{callers: callers, decimals: make(map[int64]map[common.Address]uint8)}
+}
+
+func (t *TokenDecimals) Decimals(ctx context.Context, chainID int64, token common.Address) (uint8, error) {
+	t.mu.RLock()
+	decimals, ok := t.decimals[chainID][token]
+	t.mu.RUnlock()
+	if ok {
+		return decimals, nil
+	}
+
+	caller, ok := t.callers[chainID]
+	if !ok {
+		return 0, fmt.Errorf("no caller for chain %d", chainID)
+	}
+
+	erc20, err := utils.NewErc20Caller(token, caller)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create token caller: %w", err)
+	}
+
+	if decimals, err = erc20.Decimals(&bind.CallOpts{Context: ctx}); err != nil {
+		return 0, fmt.Errorf("failed to get decimals of %s: %w", token.Hex(), err)
+	}
+
+	t.mu.Lock()
+	if t.decimals[chainID] == nil {
+		t.decimals[chainID] = make(map[common.Address]uint8)
+	}
+	t.decimals[chainID][token] = decimals
+	t.mu.Unlock()
+
+	return decimals, nil
+}
+
+func floatPrice(priceUSD float64, updatedAt time.Time) (*Price, error) {
+	if priceUSD <= 0 {
+		return nil, fmt.Errorf("%w: %f", ErrInvalidPrice, priceUSD)
+	}
+
+	scale := new(big.Float).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(_usdDecimals), nil))
+	answer, _ := new(big.Float).Mul(big.NewFloat(priceUSD), scale).Int(nil)
+	return &Price{Answer: answer, Decimals: _usdDecimals, UpdatedAt: updatedAt}, nil
+}
+
+// Float returns the price in USD
+func (p *Price) Float() float64 {
+	scale := new(big.Float).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(p.Decimals)), nil))
+	f, _ := new(big.Float).Quo(new(big.Float).SetInt(p.Answer), scale).Float64()
+	return f
+}
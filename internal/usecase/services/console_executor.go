@@ -14,6 +14,7 @@ import (
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
+	"go.temporal.io/sdk/activity"
 )
 
 type ConsoleExecutor struct {
@@ -25,6 +26,7 @@ type ConsoleExecutor struct {
 	metadata        *entity.ExecutorMetadata
 	executorPlugin  *utils.ExecutorpluginCaller
 	pluginAddress   common.Address
+	webhooks        taskWebhooks
 }
 
 func NewConsoleExecutor(
@@ -36,6 +38,7 @@ func NewConsoleExecutor(
 	client console,
 	signerAddress common.Address,
 	executoPluginAddress common.Address,
+	webhooks taskWebhooks,
 ) (*ConsoleExecutor, error) {
 	metadata, err := client.ExecutorByAddressAndChainID(ctx, executorAddress, uint64(chainID))
 	if err != nil {
@@ -61,6 +64,7 @@ func NewConsoleExecutor(
 		executorPlugin:  executorPlugin,
 		signerAddress:   signerAddress,
 		pluginAddress:   executoPluginAddress,
+		webhooks:        webhooks,
 	}, nil
 }
 
@@ -124,7 +128,7 @@ func (e *ConsoleExecutor) Execute(ctx context.Context, req *entity.SignAndExecut
 				Data:     req.Data,
 			},
 		},
-		Webhook: "",
+		Webhook: e.webhook(ctx),
 	})
 	if err != nil {
 		return "", err
@@ -144,6 +148,16 @@ func (e *ConsoleExecutor) Execute(ctx context.Context, req *entity.SignAndExecut
 	}
 }
 
+// webhook is the task callback URL of the workflow run executing the activity, if any
+func (e *ConsoleExecutor) webhook(ctx context.Context) string {
+	if e.webhooks == nil || !activity.IsActivity(ctx) {
+		return ""
+	}
+
+	execution := activity.GetInfo(ctx).WorkflowExecution
+	return e.webhooks.TaskURL(execution.ID, execution.RunID)
+}
+
 func (e *ConsoleExecutor) Subscriptions(ctx context.Context) ([]entity.ClientSubscription, error) {
 	return e.client.ActiveSubscriptions(ctx, e.metadata.Id)
 }
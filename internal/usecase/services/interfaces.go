@@ -9,7 +9,7 @@ import (
 )
 
 type orchestrator interface {
-	OrchestratorWorkflow(ctx workflow.Context, config entity.ExecuteWorkflowParams) error
+	OrchestratorWorkflow(ctx workflow.Context, config entity.ExecuteWorkflowParams) (*entity.ExecutionResult, error)
 }
 
 type keyManager interface {
@@ -30,6 +30,10 @@ type console interface {
 	Execute(ctx context.Context, req *entity.ExecuteTaskReq) (*entity.ExecuteTaskResp, error)
 }
 
+type taskWebhooks interface {
+	TaskURL(workflowID, runID string) string
+}
+
 type executors interface {
 	List() []entity.ExecutorConfig
 	Config(executor common.Address) (*entity.ExecutorConfig, error)
@@ -42,6 +46,7 @@ type schedulesRepo interface {
 		chainID int64,
 
 	) ([]entity.Schedule, error)
+	List(ctx context.Context) ([]entity.Schedule, error)
 	BySubAccountAddressesChainIDAndStatus(
 		ctx context.Context,
 		subAccounts []common.Address,
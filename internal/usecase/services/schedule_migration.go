@@ -0,0 +1,106 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Brahma-fi/brahma-builder/pkg/log"
+	"github.com/ethereum/go-ethereum/common"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/temporal"
+)
+
+// MigrateScheduleIDs moves every schedule whose ID does not match OrchestratorParams.ID to its new ID.
+//
+// The old schedule is paused before the new one is created, and the new one is never triggered
+// immediately. Interval specs are aligned to the epoch, so the new schedule fires on the same
+// cadence the old one did and no run is executed twice. A migration interrupted between the create
+// and the delete is resumed by re-running it: an existing new ID counts as migrated.
+func (s *Scheduler) MigrateScheduleIDs(ctx context.Context, dryRun bool) error {
+	logger := log.GetLogger(ctx)
+	schedules, err := s.schedulesRepo.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, schedule := range schedules {
+		if schedule.Config.Params.SubAccountAddress == (common.Address{}) {
+			continue
+		}
+
+		newID := schedule.Config.Params.ID()
+		if newID == schedule.ScheduleID {
+			continue
+		}
+
+		logger.Info(
+			"migrating schedule",
+			log.Str("scheduleID", schedule.ScheduleID),
+			log.Str("newScheduleID", newID),
+			log.Str("subaccount", schedule.Config.Params.SubAccountAddress.Hex()),
+			log.Str("subscriptionID", schedule.Config.Params.Subscription.Id),
+		)
+		if dryRun {
+			continue
+		}
+
+		if err = s.migrateSchedule(ctx, schedule.ScheduleID, newID); err != nil {
+			return fmt.Errorf("failed to migrate schedule %s: %w", schedule.ScheduleID, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *Scheduler) migrateSchedule(ctx context.Context, oldID, newID string) error {
+	handle := s.client.ScheduleClient().GetHandle(ctx, oldID)
+	description, err := handle.Describe(ctx)
+	if err != nil {
+		return err
+	}
+
+	// the memo only holds the params the schedule was created with, args hold the live ones
+	config, err := decodeScheduleArgs(description.Schedule.Action)
+	if err != nil {
+		return err
+	}
+
+	if config.Schedule == nil {
+		return fmt.Errorf("schedule %s has no schedule config", oldID)
+	}
+
+	paused, note := false, ""
+	if state := description.Schedule.State; state != nil && state.Paused {
+		// the note holds the reason the schedule was paused, sync only unpauses the ones it paused itself
+		paused, note = true, state.Note
+	}
+
+	if !paused {
+		if err = handle.Pause(ctx, client.SchedulePauseOptions{
+			Note: fmt.Sprintf("migrating to %s", newID),
+		}); err != nil {
+			return err
+		}
+	}
+
+	config.Schedule.ID = newID
+	_, err = s.create(ctx, config, false, paused, note)
+	switch {
+	case errors.Is(err, temporal.ErrScheduleAlreadyRunning):
+		// a previous run created the new schedule but failed to delete the old one
+		log.GetLogger(ctx).Info("schedule already migrated", log.Str("scheduleID", oldID), log.Str("newScheduleID", newID))
+	case err != nil:
+		if !paused {
+			if unpauseErr := handle.Unpause(ctx, client.ScheduleUnpauseOptions{
+				Note: "migration failed",
+			}); unpauseErr != nil {
+				log.GetLogger(ctx).Error("failed to unpause schedule", log.Str("scheduleID", oldID), log.Err(unpauseErr))
+			}
+		}
+
+		return err
+	}
+
+	return handle.Delete(ctx)
+}
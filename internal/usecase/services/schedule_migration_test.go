@@ -0,0 +1,238 @@
+package services
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/Brahma-fi/brahma-builder/internal/entity"
+	"github.com/ethereum/go-ethereum/common"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/converter"
+	"go.temporal.io/sdk/temporal"
+)
+
+const _testChainID = 8453
+
+var _testExecutor = common.HexToAddress("0xe0")
+
+// fakeTemporal serves schedules out of memory, every other call panics
+type fakeTemporal struct {
+	client.Client
+	schedules *fakeScheduleClient
+}
+
+func (f *fakeTemporal) ScheduleClient() client.ScheduleClient {
+	return f.schedules
+}
+
+type fakeSchedule struct {
+	config entity.ExecuteWorkflowParams
+	paused bool
+	note   string
+}
+
+type fakeScheduleClient struct {
+	client.ScheduleClient
+	byID map[string]*fakeSchedule
+}
+
+func (f *fakeScheduleClient) Create(ctx context.Context, options client.ScheduleOptions) (client.ScheduleHandle, error) {
+	if _, ok := f.byID[options.ID]; ok {
+		return nil, temporal.ErrScheduleAlreadyRunning
+	}
+
+	action := options.Action.(*client.ScheduleWorkflowAction)
+	f.byID[options.ID] = &fakeSchedule{
+		config: action.Args[0].(entity.ExecuteWorkflowParams),
+		paused: options.Paused,
+		note:   options.Note,
+	}
+	return f.GetHandle(ctx, options.ID), nil
+}
+
+func (f *fakeScheduleClient) GetHandle(_ context.Context, scheduleID string) client.ScheduleHandle {
+	return &fakeScheduleHandle{schedules: f, id: scheduleID}
+}
+
+type fakeScheduleHandle struct {
+	client.ScheduleHandle
+	schedules *fakeScheduleClient
+	id        string
+}
+
+func (h *fakeScheduleHandle) GetID() string {
+	return h.id
+}
+
+func (h *fakeScheduleHandle) Describe(context.Context) (*client.ScheduleDescription, error) {
+	schedule := h.schedules.byID[h.id]
+	payload, err := converter.GetDefaultDataConverter().ToPayload(schedule.config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &client.ScheduleDescription{Schedule: client.Schedule{
+		Action: &client.ScheduleWorkflowAction{Args: []interface{}{payload}},
+		State:  &client.ScheduleState{Paused: schedule.paused, Note: schedule.note},
+	}}, nil
+}
+
+func (h *fakeScheduleHandle) Update(ctx context.Context, options client.ScheduleUpdateOptions) error {
+	description, err := h.Describe(ctx)
+	if err != nil {
+		return err
+	}
+
+	update, err := options.DoUpdate(client.ScheduleUpdateInput{Description: *description})
+	if err != nil {
+		return err
+	}
+
+	schedule := h.schedules.byID[h.id]
+	schedule.config = update.Schedule.Action.(*client.ScheduleWorkflowAction).Args[0].(entity.ExecuteWorkflowParams)
+	schedule.paused, schedule.note = update.Schedule.State.Paused, update.Schedule.State.Note
+	return nil
+}
+
+func (h *fakeScheduleHandle) Pause(_ context.Context, options client.SchedulePauseOptions) error {
+	h.schedules.byID[h.id].paused, h.schedules.byID[h.id].note = true, options.Note
+	return nil
+}
+
+func (h *fakeScheduleHandle) Unpause(_ context.Context, options client.ScheduleUnpauseOptions) error {
+	h.schedules.byID[h.id].paused, h.schedules.byID[h.id].note = false, options.Note
+	return nil
+}
+
+func (h *fakeScheduleHandle) Delete(context.Context) error {
+	delete(h.schedules.byID, h.id)
+	return nil
+}
+
+// fakeSchedulesRepo lists the schedules of a fakeScheduleClient
+type fakeSchedulesRepo struct {
+	schedules *fakeScheduleClient
+}
+
+func (f fakeSchedulesRepo) List(context.Context) ([]entity.Schedule, error) {
+	schedules := make([]entity.Schedule, 0, len(f.schedules.byID))
+	for id, schedule := range f.schedules.byID {
+		hash, err := schedule.config.Hash()
+		if err != nil {
+			return nil, err
+		}
+
+		schedules = append(schedules, entity.Schedule{
+			Config:     schedule.config,
+			ScheduleID: id,
+			ConfigHash: hash,
+			Paused:     schedule.paused,
+			Note:       schedule.note,
+		})
+	}
+
+	return schedules, nil
+}
+
+func (f fakeSchedulesRepo) BySubAccountAddressChainIDAndStatus(
+	ctx context.Context,
+	subAccount common.Address,
+	chainID int64,
+) ([]entity.Schedule, error) {
+	return f.BySubAccountAddressesChainIDAndStatus(ctx, []common.Address{subAccount}, chainID)
+}
+
+func (f fakeSchedulesRepo) BySubAccountAddressesChainIDAndStatus(
+	ctx context.Context,
+	subAccounts []common.Address,
+	chainID int64,
+) ([]entity.Schedule, error) {
+	schedules, err := f.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return slices.DeleteFunc(schedules, func(schedule entity.Schedule) bool {
+		params := schedule.Config.Params
+		return params.ChainID != chainID || !slices.Contains(subAccounts, params.SubAccountAddress)
+	}), nil
+}
+
+// fakeConsole returns subscriptions for any executor, every other call panics
+type fakeConsole struct {
+	console
+	subscriptions []entity.ClientSubscription
+}
+
+func (f *fakeConsole) ExecutorByAddressAndChainID(
+	_ context.Context,
+	address common.Address,
+	chainID uint64,
+) (*entity.ExecutorMetadata, error) {
+	return &entity.ExecutorMetadata{Id: "registry", Executor: address.Hex(), ChainId: int(chainID)}, nil
+}
+
+func (f *fakeConsole) Subscriptions(context.Context, string) ([]entity.ClientSubscription, error) {
+	return f.subscriptions, nil
+}
+
+func TestMigratedPausedScheduleIsUnpausedOnReactivation(t *testing.T) {
+	ctx := context.Background()
+	schedules := &fakeScheduleClient{byID: make(map[string]*fakeSchedule)}
+	account := entity.ClientSubscription{
+		ChainId:           _testChainID,
+		Id:                "sub",
+		RegistryId:        "registry",
+		Status:            entity.SubscriptionStatusPaused,
+		SubAccountAddress: common.HexToAddress("0x5a").Hex(),
+	}
+	console := &fakeConsole{subscriptions: []entity.ClientSubscription{account}}
+	s := NewScheduler(
+		&fakeTemporal{schedules: schedules},
+		console,
+		entity.NewExecutorConfigRepo(entity.ExecutorConfigs{{
+			Address: _testExecutor.Hex(),
+			ChainID: _testChainID,
+			Every:   "1h",
+		}}),
+		fakeSchedulesRepo{schedules: schedules},
+	)
+
+	config, err := s.createWorkflowConfig(account, &entity.ExecutorMetadata{Executor: _testExecutor.Hex()}, _testChainID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a schedule paused by sync under an ID from before schedules were keyed by subscription
+	config.Schedule.ID = "legacy"
+	schedules.byID["legacy"] = &fakeSchedule{
+		config: config,
+		paused: true,
+		note:   subscriptionStatusNote(entity.SubscriptionStatusPaused),
+	}
+
+	if err = s.MigrateScheduleIDs(ctx, false); err != nil {
+		t.Fatal(err)
+	}
+
+	newID := config.Params.ID()
+	migrated, ok := schedules.byID[newID]
+	if !ok || len(schedules.byID) != 1 {
+		t.Fatalf("schedule not migrated to %s", newID)
+	}
+
+	if !migrated.paused || migrated.note != subscriptionStatusNote(entity.SubscriptionStatusPaused) {
+		t.Fatalf("migrated schedule paused %t with note %q", migrated.paused, migrated.note)
+	}
+
+	console.subscriptions[0].Status = entity.SubscriptionStatusActive
+	report, err := s.SyncExecutor(ctx, entity.SyncTarget{ExecutorAddress: _testExecutor, ChainID: _testChainID}, func(entity.SyncProgress) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(report.Unpaused) != 1 || len(report.Failed) != 0 || migrated.paused {
+		t.Fatalf("reactivated subscription's schedule not unpaused, report %+v", report)
+	}
+}
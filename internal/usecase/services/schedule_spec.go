@@ -0,0 +1,376 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Brahma-fi/brahma-builder/internal/entity"
+	"github.com/robfig/cron"
+	"go.temporal.io/sdk/client"
+)
+
+var (
+	ErrInvalidScheduleSpec = errors.New("invalid schedule spec")
+
+	_cronWithSecondsParser = cron.NewParser(
+		cron.Second | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow,
+	)
+)
+
+type calendarField struct {
+	name     string
+	min, max int
+}
+
+var (
+	_calendarSecond     = calendarField{name: "Second", min: 0, max: 59}
+	_calendarMinute     = calendarField{name: "Minute", min: 0, max: 59}
+	_calendarHour       = calendarField{name: "Hour", min: 0, max: 23}
+	_calendarDayOfMonth = calendarField{name: "DayOfMonth", min: 1, max: 31}
+	_calendarMonth      = calendarField{name: "Month", min: 1, max: 12}
+	_calendarYear       = calendarField{name: "Year", min: 1970, max: 9999}
+	_calendarDayOfWeek  = calendarField{name: "DayOfWeek", min: 0, max: 6}
+)
+
+// parseScheduleOptions builds the schedule config of a subscription out of its custom interval options.
+// Options which don't parse are an error, defaultEvery is only used when no Every is set.
+func parseScheduleOptions(
+	custom *entity.CustomIntervalOptions,
+	defaultEvery time.Duration,
+) (*entity.ScheduledWorkflowConfig, error) {
+	config := &entity.ScheduledWorkflowConfig{Every: defaultEvery}
+	if custom == nil {
+		return config, nil
+	}
+
+	if len(custom.Cron) != 0 || len(custom.Calendars) != 0 {
+		// calendar based schedules only run on the executor interval when asked to
+		config.Every = 0
+	}
+
+	if custom.Every != "" {
+		every, err := parseEvery(custom.Every)
+		if err != nil {
+			return nil, err
+		}
+		config.Every = every
+	}
+
+	if custom.Jitter != "" {
+		jitter, err := time.ParseDuration(custom.Jitter)
+		if err != nil || jitter < 0 {
+			return nil, fmt.Errorf("%w: Jitter %q is not a non-negative duration", ErrInvalidScheduleSpec, custom.Jitter)
+		}
+		config.Jitter = jitter
+	}
+
+	if custom.TimeZone != "" {
+		if _, err := time.LoadLocation(custom.TimeZone); err != nil {
+			return nil, fmt.Errorf("%w: TimeZone %q: %w", ErrInvalidScheduleSpec, custom.TimeZone, err)
+		}
+		config.TimeZone = custom.TimeZone
+	}
+
+	for i, expr := range custom.Cron {
+		if err := validateCron(expr); err != nil {
+			return nil, fmt.Errorf("%w: Cron[%d] %q: %w", ErrInvalidScheduleSpec, i, expr, err)
+		}
+		config.Cron = append(config.Cron, strings.TrimSpace(expr))
+	}
+
+	for i, calendar := range custom.Calendars {
+		spec, err := parseCalendar(calendar)
+		if err != nil {
+			return nil, fmt.Errorf("%w: Calendars[%d]: %w", ErrInvalidScheduleSpec, i, err)
+		}
+		config.Calendars = append(config.Calendars, spec)
+	}
+
+	for i, calendar := range custom.Exclude {
+		spec, err := parseCalendar(calendar)
+		if err != nil {
+			return nil, fmt.Errorf("%w: Exclude[%d]: %w", ErrInvalidScheduleSpec, i, err)
+		}
+		config.Skip = append(config.Skip, spec)
+	}
+
+	var err error
+	if config.StartAt, err = parseSpecTime("StartAt", custom.StartAt); err != nil {
+		return nil, err
+	}
+
+	if config.EndAt, err = parseSpecTime("EndAt", custom.EndAt); err != nil {
+		return nil, err
+	}
+
+	if config.StartAt != nil && config.EndAt != nil && !config.EndAt.After(*config.StartAt) {
+		return nil, fmt.Errorf("%w: EndAt %s is not after StartAt %s", ErrInvalidScheduleSpec, custom.EndAt, custom.StartAt)
+	}
+
+	if config.Every == 0 && len(config.Cron) == 0 && len(config.Calendars) == 0 {
+		return nil, fmt.Errorf("%w: no interval, cron or calendar to run on", ErrInvalidScheduleSpec)
+	}
+
+	return config, nil
+}
+
+// applySubscriptionExpiry ends the schedule gracePeriod after the subscription expires, so that it stops
+// without waiting for a sync to notice the expiry. A windDownBefore adds a one off run that long before expiry.
+func applySubscriptionExpiry(
+	config *entity.ScheduledWorkflowConfig,
+	account entity.ClientSubscription,
+	gracePeriod time.Duration,
+	windDownBefore time.Duration,
+) error {
+	expiresAt, ok := account.ExpiresAt()
+	if !ok {
+		return nil
+	}
+
+	endAt := expiresAt.Add(gracePeriod)
+	if config.EndAt == nil || endAt.Before(*config.EndAt) {
+		config.EndAt = &endAt
+	}
+
+	if windDownBefore <= 0 {
+		return nil
+	}
+
+	windDownAt := expiresAt.Add(-windDownBefore)
+	if (config.StartAt != nil && windDownAt.Before(*config.StartAt)) || !windDownAt.Before(*config.EndAt) {
+		return nil
+	}
+
+	// calendars are matched in the schedule's time zone
+	location := time.UTC
+	if config.TimeZone != "" {
+		var err error
+		if location, err = time.LoadLocation(config.TimeZone); err != nil {
+			return fmt.Errorf("%w: TimeZone %q: %w", ErrInvalidScheduleSpec, config.TimeZone, err)
+		}
+	}
+
+	at := windDownAt.In(location)
+	config.WindDownAt = &windDownAt
+	config.Calendars = append(config.Calendars, client.ScheduleCalendarSpec{
+		Second:     []client.ScheduleRange{{Start: at.Second(), End: at.Second(), Step: 1}},
+		Minute:     []client.ScheduleRange{{Start: at.Minute(), End: at.Minute(), Step: 1}},
+		Hour:       []client.ScheduleRange{{Start: at.Hour(), End: at.Hour(), Step: 1}},
+		DayOfMonth: []client.ScheduleRange{{Start: at.Day(), End: at.Day(), Step: 1}},
+		Month:      []client.ScheduleRange{{Start: int(at.Month()), End: int(at.Month()), Step: 1}},
+		Year:       []client.ScheduleRange{{Start: at.Year(), End: at.Year(), Step: 1}},
+		DayOfWeek:  []client.ScheduleRange{{Start: 0, End: 6, Step: 1}},
+		Comment:    "wind down before subscription expiry",
+	})
+
+	return nil
+}
+
+func scheduleSpec(config entity.ExecuteWorkflowParams) client.ScheduleSpec {
+	spec := client.ScheduleSpec{
+		Calendars:       config.Schedule.Calendars,
+		CronExpressions: config.Schedule.Cron,
+		Skip:            config.Schedule.Skip,
+		Jitter:          config.Schedule.Jitter,
+		TimeZoneName:    config.Schedule.TimeZone,
+	}
+
+	if spec.Jitter == 0 {
+		spec.Jitter = config.Schedule.Policies.Jitter
+	}
+
+	if config.Schedule.Every > 0 {
+		spec.Intervals = []client.ScheduleIntervalSpec{
+			{
+				Every: config.Schedule.Every,
+			},
+		}
+	}
+
+	if config.Schedule.StartAt != nil {
+		spec.StartAt = *config.Schedule.StartAt
+	}
+
+	if config.Schedule.EndAt != nil {
+		spec.EndAt = *config.Schedule.EndAt
+	}
+
+	return spec
+}
+
+// parseEvery parses a positive interval, either as a duration or as a number of seconds
+func parseEvery(value string) (time.Duration, error) {
+	every, err := time.ParseDuration(value)
+	if err != nil {
+		seconds, atoiErr := strconv.Atoi(value)
+		if atoiErr != nil {
+			return 0, fmt.Errorf("%w: Every %q is neither a duration nor a number of seconds", ErrInvalidScheduleSpec, value)
+		}
+		every = time.Duration(seconds) * time.Second
+	}
+
+	if every <= 0 {
+		return 0, fmt.Errorf("%w: Every %q is not a positive interval", ErrInvalidScheduleSpec, value)
+	}
+
+	return every, nil
+}
+
+func parseSpecTime(name, value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s %q is not an RFC3339 time", ErrInvalidScheduleSpec, name, value)
+	}
+
+	t = t.UTC()
+	return &t, nil
+}
+
+// validateCron accepts the cron expressions Temporal does: 5 to 7 fields or a descriptor,
+// optionally prefixed with a time zone and suffixed with a comment.
+func validateCron(expr string) error {
+	expr = strings.TrimSpace(expr)
+	if i := strings.Index(expr, "#"); i >= 0 {
+		expr = strings.TrimSpace(expr[:i])
+	}
+
+	if strings.HasPrefix(expr, "CRON_TZ=") || strings.HasPrefix(expr, "TZ=") {
+		tz, rest, _ := strings.Cut(expr, " ")
+		_, name, _ := strings.Cut(tz, "=")
+		if _, err := time.LoadLocation(name); err != nil {
+			return err
+		}
+		expr = strings.TrimSpace(rest)
+	}
+
+	if strings.HasPrefix(expr, "@") {
+		_, err := cron.ParseStandard(expr)
+		return err
+	}
+
+	fields := strings.Fields(expr)
+	switch len(fields) {
+	case 5:
+		_, err := cron.ParseStandard(expr)
+		return err
+	case 6:
+		if _, err := cron.ParseStandard(strings.Join(fields[:5], " ")); err != nil {
+			return err
+		}
+		_, err := parseRanges(_calendarYear, fields[5])
+		return err
+	case 7:
+		if _, err := _cronWithSecondsParser.Parse(strings.Join(fields[:6], " ")); err != nil {
+			return err
+		}
+		_, err := parseRanges(_calendarYear, fields[6])
+		return err
+	default:
+		return fmt.Errorf("expected 5 to 7 fields, got %d", len(fields))
+	}
+}
+
+func parseCalendar(opts entity.CalendarOptions) (client.ScheduleCalendarSpec, error) {
+	spec := client.ScheduleCalendarSpec{Comment: opts.Comment}
+	fields := []struct {
+		field  calendarField
+		value  string
+		target *[]client.ScheduleRange
+	}{
+		{_calendarSecond, opts.Second, &spec.Second},
+		{_calendarMinute, opts.Minute, &spec.Minute},
+		{_calendarHour, opts.Hour, &spec.Hour},
+		{_calendarDayOfMonth, opts.DayOfMonth, &spec.DayOfMonth},
+		{_calendarMonth, opts.Month, &spec.Month},
+		{_calendarYear, opts.Year, &spec.Year},
+		{_calendarDayOfWeek, opts.DayOfWeek, &spec.DayOfWeek},
+	}
+
+	for _, f := range fields {
+		ranges, err := parseRanges(f.field, f.value)
+		if err != nil {
+			return client.ScheduleCalendarSpec{}, err
+		}
+		*f.target = ranges
+	}
+
+	return spec, nil
+}
+
+// parseRanges parses a comma separated list of "*", "v", "a-b" with an optional "/step".
+// An empty value returns no ranges, which leaves the field at its Temporal default.
+func parseRanges(field calendarField, value string) ([]client.ScheduleRange, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+
+	ranges := make([]client.ScheduleRange, 0)
+	for _, part := range strings.Split(value, ",") {
+		r, err := parseRange(field, strings.TrimSpace(part))
+		if err != nil {
+			return nil, err
+		}
+		ranges = append(ranges, r)
+	}
+
+	return ranges, nil
+}
+
+func parseRange(field calendarField, part string) (client.ScheduleRange, error) {
+	invalid := func(reason string) error {
+		return fmt.Errorf("%s %q: %s", field.name, part, reason)
+	}
+
+	bounds, stepStr, hasStep := strings.Cut(part, "/")
+	r := client.ScheduleRange{Step: 1}
+	if hasStep {
+		step, err := strconv.Atoi(stepStr)
+		if err != nil || step <= 0 {
+			return r, invalid("step must be a positive number")
+		}
+		r.Step = step
+	}
+
+	switch {
+	case bounds == "*":
+		r.Start, r.End = field.min, field.max
+	case strings.Contains(bounds, "-"):
+		startStr, endStr, _ := strings.Cut(bounds, "-")
+		start, err := strconv.Atoi(startStr)
+		if err != nil {
+			return r, invalid("range start is not a number")
+		}
+		end, err := strconv.Atoi(endStr)
+		if err != nil {
+			return r, invalid("range end is not a number")
+		}
+		r.Start, r.End = start, end
+	default:
+		v, err := strconv.Atoi(bounds)
+		if err != nil {
+			return r, invalid("value is not a number")
+		}
+		r.Start, r.End = v, v
+		if hasStep {
+			r.End = field.max
+		}
+	}
+
+	if r.Start < field.min || r.End > field.max {
+		return r, invalid(fmt.Sprintf("out of range %d-%d", field.min, field.max))
+	}
+
+	if r.End < r.Start {
+		return r, invalid("range end is before its start")
+	}
+
+	return r, nil
+}
@@ -0,0 +1,94 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Brahma-fi/brahma-builder/internal/entity"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestParseScheduleOptionsEvery(t *testing.T) {
+	tests := []struct {
+		name    string
+		custom  *entity.CustomIntervalOptions
+		want    time.Duration
+		wantErr bool
+	}{
+		{name: "no options", want: time.Hour},
+		{name: "duration", custom: &entity.CustomIntervalOptions{Every: "30m"}, want: 30 * time.Minute},
+		{name: "seconds", custom: &entity.CustomIntervalOptions{Every: "90"}, want: 90 * time.Second},
+		{name: "malformed", custom: &entity.CustomIntervalOptions{Every: "hourly"}, wantErr: true},
+		{name: "zero", custom: &entity.CustomIntervalOptions{Every: "0s"}, wantErr: true},
+		{name: "negative", custom: &entity.CustomIntervalOptions{Every: "-60"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := parseScheduleOptions(tt.custom, time.Hour)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidScheduleSpec) {
+					t.Fatalf("got %v, want %v", err, ErrInvalidScheduleSpec)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if config.Every != tt.want {
+				t.Fatalf("got every %s, want %s", config.Every, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffChainReportsInvalidScheduleMetadata(t *testing.T) {
+	schedules := &fakeScheduleClient{byID: make(map[string]*fakeSchedule)}
+	s := NewScheduler(
+		&fakeTemporal{schedules: schedules},
+		&fakeConsole{},
+		entity.NewExecutorConfigRepo(entity.ExecutorConfigs{{Address: _testExecutor.Hex(), ChainID: _testChainID, Every: "1h"}}),
+		fakeSchedulesRepo{schedules: schedules},
+	)
+
+	accounts := make([]entity.ClientSubscription, 0)
+	for id, metadata := range map[string]string{"malformed": `{"Every":"hourly"}`, "truncated": `{"Every":`, "valid": `{"Every":"30m"}`} {
+		accounts = append(accounts, entity.ClientSubscription{
+			ChainId:           _testChainID,
+			Id:                id,
+			Metadata:          json.RawMessage(metadata),
+			RegistryId:        "registry",
+			Status:            entity.SubscriptionStatusActive,
+			SubAccountAddress: common.BigToAddress(common.Big1).Hex(),
+		})
+	}
+
+	plan, err := s.DiffChain(context.Background(), _testChainID, &entity.SubscriptionSnapshot{
+		Accounts:         accounts,
+		ExecutorMetadata: map[string]*entity.ExecutorMetadata{"registry": {Id: "registry", Executor: _testExecutor.Hex()}},
+		SyncedExecutors:  []common.Address{_testExecutor},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(plan.Mutations) != 1 || plan.Mutations[0].Key.SubscriptionID != "valid" {
+		t.Fatalf("got mutations %+v, want the valid subscription's create only", plan.Mutations)
+	}
+
+	if len(plan.Report.Failed) != 2 {
+		t.Fatalf("got %d failures, want 2", len(plan.Report.Failed))
+	}
+
+	for _, failed := range plan.Report.Failed {
+		if !strings.Contains(failed.Reason, ErrInvalidScheduleSpec.Error()) {
+			t.Fatalf("subscription %s failed with %q", failed.Key.SubscriptionID, failed.Reason)
+		}
+	}
+}
@@ -1,20 +1,27 @@
 package services
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
-	"strconv"
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
 	"strings"
 	"time"
 
 	"github.com/Brahma-fi/brahma-builder/internal/entity"
+	"github.com/Brahma-fi/brahma-builder/internal/repo"
 	"github.com/Brahma-fi/brahma-builder/internal/usecase/workflows"
 	"github.com/Brahma-fi/brahma-builder/pkg/log"
 	"github.com/Brahma-fi/brahma-builder/pkg/utils"
 	"github.com/ethereum/go-ethereum/common"
+	commonpb "go.temporal.io/api/common/v1"
 	"go.temporal.io/api/enums/v1"
 	"go.temporal.io/api/operatorservice/v1"
 	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/converter"
 	"go.temporal.io/sdk/temporal"
 )
 
@@ -26,71 +33,77 @@ type Scheduler struct {
 }
 
 func NewScheduler(
-	ctx context.Context,
 	client client.Client,
 	console console,
 	executors executors,
 	schedulesRepo schedulesRepo,
-) (*Scheduler, error) {
-	logger := log.NewLogger("scheduler", "debug")
-	// ensure search attributes are always ready
-	resp, err := client.OperatorService().AddSearchAttributes(ctx, &operatorservice.AddSearchAttributesRequest{
+) *Scheduler {
+	return &Scheduler{client: client, console: console, executors: executors, schedulesRepo: schedulesRepo}
+}
+
+// EnsureSearchAttributes registers the search attributes schedules and their runs are indexed by.
+// It writes to the namespace, so only commands creating or updating schedules call it.
+func (s *Scheduler) EnsureSearchAttributes(ctx context.Context) error {
+	resp, err := s.client.OperatorService().AddSearchAttributes(ctx, &operatorservice.AddSearchAttributesRequest{
 		SearchAttributes: map[string]enums.IndexedValueType{
 			entity.SearchAttrKeyChainID:           enums.INDEXED_VALUE_TYPE_INT,
 			entity.SearchAttrKeySubAccountAddress: enums.INDEXED_VALUE_TYPE_KEYWORD,
 			entity.SearchAttrKeyExecutorAddress:   enums.INDEXED_VALUE_TYPE_KEYWORD,
 			entity.SearchAttrKeyExecutorID:        enums.INDEXED_VALUE_TYPE_KEYWORD,
+			entity.SearchAttrKeyConfigHash:        enums.INDEXED_VALUE_TYPE_KEYWORD,
+			repo.KeyExecutionStatus:               enums.INDEXED_VALUE_TYPE_KEYWORD,
 		},
 		Namespace: entity.DefaultNamespace,
 	})
 	if err != nil && !strings.Contains(err.Error(), "already exists") {
-		return nil, err
+		return err
 	}
 
-	logger.Info("AddSearchAttributes", log.Str("resp", resp.String()))
-
-	return &Scheduler{client: client, console: console, executors: executors, schedulesRepo: schedulesRepo}, nil
+	log.GetLogger(ctx).Info("AddSearchAttributes", log.Str("resp", resp.String()))
+	return nil
 }
 
 func (s *Scheduler) Run(ctx context.Context, config entity.ExecuteWorkflowParams) (string, error) {
-	o := workflows.Orchestrator{}
-
 	config.Schedule.ID = config.Params.ID()
+	return s.create(ctx, config, true, false, "")
+}
 
-	searchAttributes := temporal.NewSearchAttributes(
-		temporal.NewSearchAttributeKeyKeyword(entity.SearchAttrKeySubAccountAddress).ValueSet(config.Params.SubAccountAddress.Hex()),
-		temporal.NewSearchAttributeKeyKeyword(entity.SearchAttrKeyExecutorAddress).ValueSet(config.Params.ExecutorAddress.Hex()),
-		temporal.NewSearchAttributeKeyInt64(entity.SearchAttrKeyChainID).ValueSet(config.Params.ChainID),
-		temporal.NewSearchAttributeKeyKeyword(entity.SearchAttrKeyExecutorID).ValueSet(config.Params.ExecutorID),
-	)
+// create creates the schedule config.Schedule.ID. Its note is the subscription metadata unless note is set.
+func (s *Scheduler) create(
+	ctx context.Context,
+	config entity.ExecuteWorkflowParams,
+	triggerImmediately bool,
+	paused bool,
+	note string,
+) (string, error) {
+	searchAttributes, err := scheduleSearchAttributes(config)
+	if err != nil {
+		return "", err
+	}
 
 	memo, err := utils.Struct2Map(config)
 	if err != nil {
 		return "", err
 	}
 
+	if note == "" {
+		note = string(config.Params.Subscription.Metadata)
+	}
+
+	policies := schedulePolicies(config)
 	schedule, err := s.client.
 		ScheduleClient().Create(ctx, client.ScheduleOptions{
-		ID: config.Schedule.ID,
-		Spec: client.ScheduleSpec{
-			Intervals: []client.ScheduleIntervalSpec{
-				{
-					Every: config.Schedule.Every,
-				},
-			},
-		},
-		Action: &client.ScheduleWorkflowAction{
-			TaskQueue:             entity.BaseTaskQueue,
-			Workflow:              o.OrchestratorWorkflow,
-			Args:                  []interface{}{config},
-			TypedSearchAttributes: searchAttributes,
-		},
-		Overlap:               enums.SCHEDULE_OVERLAP_POLICY_SKIP,
-		PauseOnFailure:        false,
-		Note:                  string(config.Params.Subscription.Metadata),
+		ID:                    config.Schedule.ID,
+		Spec:                  scheduleSpec(config),
+		Action:                scheduleAction(config, searchAttributes, memo),
+		Overlap:               policies.Overlap,
+		CatchupWindow:         policies.CatchupWindow,
+		PauseOnFailure:        policies.PauseOnFailure,
+		Note:                  note,
 		TypedSearchAttributes: searchAttributes,
 		Memo:                  memo,
-		TriggerImmediately:    true,
+		TriggerImmediately:    triggerImmediately && config.Schedule.Policies.TriggerImmediately,
+		Paused:                paused,
 	})
 	if err != nil {
 		return "", err
@@ -99,126 +112,162 @@ func (s *Scheduler) Run(ctx context.Context, config entity.ExecuteWorkflowParams
 	return schedule.GetID(), nil
 }
 
-func (s *Scheduler) Sync(ctx context.Context) error {
-	activeAccounts, executorMetadata, err := s.fetchActiveAccountsAndMetadata(ctx)
+// Update applies config in place to the existing schedule config.Schedule.ID.
+// Spec, policies, workflow args, workflow memo and search attributes are replaced, while
+// the schedule state (paused, remaining actions) is left untouched.
+func (s *Scheduler) Update(ctx context.Context, config entity.ExecuteWorkflowParams) error {
+	logger := log.GetLogger(ctx)
+	searchAttributes, err := scheduleSearchAttributes(config)
 	if err != nil {
 		return err
 	}
 
-	accountsByChain := groupAccountsByChain(activeAccounts)
-
-	for chainID, accounts := range accountsByChain {
-		if err := s.syncChain(ctx, chainID, accounts, executorMetadata); err != nil {
-			return err
-		}
+	memo, err := utils.Struct2Map(config)
+	if err != nil {
+		return err
 	}
 
-	return nil
-}
+	return s.client.ScheduleClient().GetHandle(ctx, config.Schedule.ID).Update(ctx, client.ScheduleUpdateOptions{
+		DoUpdate: func(input client.ScheduleUpdateInput) (*client.ScheduleUpdate, error) {
+			schedule := input.Description.Schedule
+			current, err := decodeScheduleArgs(schedule.Action)
+			if err != nil {
+				logger.Warn(
+					"failed to decode current schedule args",
+					log.Str("scheduleID", config.Schedule.ID),
+					log.Err(err),
+				)
+			} else {
+				logger.Info(
+					"schedule drift",
+					log.Str("scheduleID", config.Schedule.ID),
+					log.Any("fields", workflowConfigDrift(current, config)),
+				)
+			}
 
-func (s *Scheduler) fetchActiveAccountsAndMetadata(ctx context.Context) ([]entity.ClientSubscription, map[string]*entity.ExecutorMetadata, error) {
-	registeredExecutors := s.executors.List()
-	activeAccounts := make([]entity.ClientSubscription, 0)
-	executorMetadata := make(map[string]*entity.ExecutorMetadata)
+			spec := scheduleSpec(config)
+			schedule.Spec = &spec
+			schedule.Action = scheduleAction(config, searchAttributes, memo)
+			schedule.Policy = schedulePolicies(config)
+			// a paused schedule's note holds the reason it was paused
+			if schedule.State != nil && !schedule.State.Paused {
+				schedule.State.Note = string(config.Params.Subscription.Metadata)
+			}
 
-	for _, e := range registeredExecutors {
-		metadata, err := s.console.ExecutorByAddressAndChainID(ctx, common.HexToAddress(e.Address), uint64(e.ChainID))
-		if err != nil {
-			return nil, nil, err
-		}
+			return &client.ScheduleUpdate{
+				Schedule:              &schedule,
+				TypedSearchAttributes: &searchAttributes,
+			}, nil
+		},
+	})
+}
 
-		executorMetadata[metadata.Id] = metadata
-		subscription, err := s.console.Subscriptions(ctx, metadata.Id)
-		if err != nil {
-			return nil, nil, err
-		}
+func scheduleAction(
+	config entity.ExecuteWorkflowParams,
+	searchAttributes temporal.SearchAttributes,
+	memo map[string]interface{},
+) *client.ScheduleWorkflowAction {
+	o := workflows.Orchestrator{}
+	return &client.ScheduleWorkflowAction{
+		TaskQueue:             entity.BaseTaskQueue,
+		Workflow:              o.OrchestratorWorkflow,
+		Args:                  []interface{}{config},
+		Memo:                  memo,
+		TypedSearchAttributes: searchAttributes,
+	}
+}
 
-		activeAccounts = append(activeAccounts, subscription...)
+func schedulePolicies(config entity.ExecuteWorkflowParams) *client.SchedulePolicies {
+	policies := config.Schedule.Policies
+	overlap := policies.Overlap
+	if overlap == enums.SCHEDULE_OVERLAP_POLICY_UNSPECIFIED {
+		overlap = enums.SCHEDULE_OVERLAP_POLICY_SKIP
 	}
 
-	return activeAccounts, executorMetadata, nil
+	return &client.SchedulePolicies{
+		Overlap:        overlap,
+		CatchupWindow:  policies.CatchupWindow,
+		PauseOnFailure: policies.PauseOnFailure,
+	}
 }
 
-func groupAccountsByChain(accounts []entity.ClientSubscription) map[int64][]entity.ClientSubscription {
-	accountsByChain := make(map[int64][]entity.ClientSubscription)
-	for _, account := range accounts {
-		chainID := int64(account.ChainId)
-		accountsByChain[chainID] = append(accountsByChain[chainID], account)
+func scheduleSearchAttributes(config entity.ExecuteWorkflowParams) (temporal.SearchAttributes, error) {
+	configHash, err := config.Hash()
+	if err != nil {
+		return temporal.SearchAttributes{}, err
 	}
-	return accountsByChain
+
+	return temporal.NewSearchAttributes(
+		temporal.NewSearchAttributeKeyKeyword(entity.SearchAttrKeySubAccountAddress).ValueSet(config.Params.SubAccountAddress.Hex()),
+		temporal.NewSearchAttributeKeyKeyword(entity.SearchAttrKeyExecutorAddress).ValueSet(config.Params.ExecutorAddress.Hex()),
+		temporal.NewSearchAttributeKeyInt64(entity.SearchAttrKeyChainID).ValueSet(config.Params.ChainID),
+		temporal.NewSearchAttributeKeyKeyword(entity.SearchAttrKeyExecutorID).ValueSet(config.Params.ExecutorID),
+		temporal.NewSearchAttributeKeyKeyword(entity.SearchAttrKeyConfigHash).ValueSet(configHash),
+	), nil
 }
 
-func (s *Scheduler) syncChain(
-	ctx context.Context,
-	chainID int64,
-	accounts []entity.ClientSubscription,
-	executorMetadata map[string]*entity.ExecutorMetadata,
-) error {
-	logger := log.GetLogger(ctx)
-	logger.Info("syncing chain", log.Int("chainID", int(chainID)), log.Any("account", accounts))
-	subAccounts := extractSubAccounts(accounts)
+// decodeScheduleArgs reads the workflow params a schedule currently starts its runs with.
+func decodeScheduleArgs(action client.ScheduleAction) (entity.ExecuteWorkflowParams, error) {
+	config := entity.ExecuteWorkflowParams{}
+	workflowAction, ok := action.(*client.ScheduleWorkflowAction)
+	if !ok || len(workflowAction.Args) == 0 {
+		return config, errors.New("schedule has no workflow args")
+	}
 
-	existingSchedules, err := s.schedulesRepo.BySubAccountAddressesChainIDAndStatus(ctx, subAccounts, chainID)
-	if err != nil {
-		return err
+	payload, ok := workflowAction.Args[0].(*commonpb.Payload)
+	if !ok {
+		return config, errors.New("unexpected schedule args type")
 	}
 
-	existingScheduleMap := createExistingScheduleMap(ctx, existingSchedules)
-	if err := s.createNewSchedules(ctx, accounts, executorMetadata, existingScheduleMap, chainID); err != nil {
-		return err
+	if err := converter.GetDefaultDataConverter().FromPayload(payload, &config); err != nil {
+		return config, err
 	}
 
-	return s.terminateCancelledSchedules(ctx, existingSchedules, accounts)
+	return config, nil
 }
 
-func extractSubAccounts(accounts []entity.ClientSubscription) []common.Address {
-	subAccounts := make([]common.Address, len(accounts))
-	for i, account := range accounts {
-		subAccounts[i] = common.HexToAddress(account.SubAccountAddress)
+// workflowConfigDrift lists the fields which differ between the params a schedule runs with
+// and the params derived from the console subscription.
+func workflowConfigDrift(current, desired entity.ExecuteWorkflowParams) []string {
+	drift := make([]string, 0)
+	if current.Schedule == nil || desired.Schedule == nil {
+		drift = append(drift, "schedule")
+	} else {
+		if !reflect.DeepEqual(scheduleSpec(current), scheduleSpec(desired)) {
+			drift = append(drift, "spec")
+		}
+
+		if current.Schedule.Policies != desired.Schedule.Policies {
+			drift = append(drift, "policies")
+		}
 	}
-	return subAccounts
-}
 
-func createExistingScheduleMap(ctx context.Context, existingSchedules []entity.Schedule) map[common.Address]bool {
-	logger := log.GetLogger(ctx)
-	existingScheduleMap := make(map[common.Address]bool)
-	for _, schedule := range existingSchedules {
-		logger.Info(
-			"existing schedule",
-			log.Str("id", schedule.ScheduleID),
-			log.Str("subacc", schedule.Config.Params.SubAccountAddress.Hex()),
-		)
-		existingScheduleMap[schedule.Config.Params.SubAccountAddress] = true
+	currentSub, desiredSub := current.Params.Subscription, desired.Params.Subscription
+	if !bytes.Equal(currentSub.Metadata, desiredSub.Metadata) {
+		drift = append(drift, "metadata")
 	}
-	return existingScheduleMap
-}
 
-func (s *Scheduler) createNewSchedules(
-	ctx context.Context,
-	accounts []entity.ClientSubscription,
-	executorMetadata map[string]*entity.ExecutorMetadata,
-	existingScheduleMap map[common.Address]bool,
-	chainID int64,
-) error {
-	logger := log.GetLogger(ctx)
-	for _, account := range accounts {
-		subAccountAddress := common.HexToAddress(account.SubAccountAddress)
-		if !existingScheduleMap[subAccountAddress] && account.Status == 2 {
-			config, err := s.createWorkflowConfig(account, executorMetadata[account.RegistryId], chainID)
-			if err != nil {
-				return err
-			}
-			logger.Info(
-				"creating new schedule",
-				log.Str("scheduleID", config.Schedule.ID),
-				log.Str("subaccount", account.SubAccountAddress),
-			)
-			if _, err = s.Run(ctx, config); err != nil {
-				return err
-			}
-		}
+	if !maps.Equal(currentSub.TokenLimits, desiredSub.TokenLimits) {
+		drift = append(drift, "tokenLimits")
 	}
-	return nil
+
+	if !maps.Equal(currentSub.TokenInputs, desiredSub.TokenInputs) {
+		drift = append(drift, "tokenInputs")
+	}
+
+	if currentSub.Status != desiredSub.Status {
+		drift = append(drift, "status")
+	}
+
+	if currentSub.FeeAmount != desiredSub.FeeAmount || currentSub.FeeToken != desiredSub.FeeToken {
+		drift = append(drift, "fee")
+	}
+
+	if currentSub.Duration != desiredSub.Duration || currentSub.CommitHash != desiredSub.CommitHash {
+		drift = append(drift, "subscription")
+	}
+
+	return drift
 }
 
 func (s *Scheduler) createWorkflowConfig(
@@ -239,23 +288,32 @@ func (s *Scheduler) createWorkflowConfig(
 	}
 
 	// Check for custom interval options in the account metadata
-	custom := &entity.CustomIntervalOptions{}
-	if err = json.Unmarshal(account.Metadata, custom); err == nil && custom.Every != "" {
-		// Try to parse custom.Every as a duration string
-		customDuration, err := time.ParseDuration(custom.Every)
-		if err == nil {
-			// If parsing as duration succeeds, use the custom duration
-			duration = customDuration
-		} else {
-			// If parsing as duration fails, try to parse as an integer (seconds)
-			seconds, err := strconv.Atoi(custom.Every)
-			if err == nil {
-				duration = time.Duration(seconds) * time.Second
-			}
-			// If both parsing attempts fail, we keep the default duration
+	var custom *entity.CustomIntervalOptions
+	if len(account.Metadata) != 0 {
+		custom = &entity.CustomIntervalOptions{}
+		if err = json.Unmarshal(account.Metadata, custom); err != nil {
+			return entity.ExecuteWorkflowParams{}, fmt.Errorf("%w: %w", ErrInvalidScheduleSpec, err)
 		}
 	}
 
+	schedule, err := parseScheduleOptions(custom, duration)
+	if err != nil {
+		return entity.ExecuteWorkflowParams{}, err
+	}
+
+	gracePeriod, windDownBefore, err := cfg.SubscriptionWindow()
+	if err != nil {
+		return entity.ExecuteWorkflowParams{}, err
+	}
+
+	if err = applySubscriptionExpiry(schedule, account, gracePeriod, windDownBefore); err != nil {
+		return entity.ExecuteWorkflowParams{}, err
+	}
+
+	if schedule.Policies, err = cfg.SchedulePolicies(); err != nil {
+		return entity.ExecuteWorkflowParams{}, err
+	}
+
 	// Create and return the ExecuteWorkflowParams
 	return entity.ExecuteWorkflowParams{
 		Params: entity.OrchestratorParams{
@@ -265,35 +323,6 @@ func (s *Scheduler) createWorkflowConfig(
 			ChainID:           chainID,
 			Subscription:      account,
 		},
-		Schedule: &entity.ScheduledWorkflowConfig{
-			Every: duration,
-		},
+		Schedule: schedule,
 	}, nil
 }
-
-func (s *Scheduler) terminateCancelledSchedules(
-	ctx context.Context,
-	existingSchedules []entity.Schedule,
-	accounts []entity.ClientSubscription,
-) error {
-	logger := log.GetLogger(ctx)
-	activeSubAccounts := make(map[common.Address]entity.ClientSubscription)
-	for _, account := range accounts {
-		activeSubAccounts[common.HexToAddress(account.SubAccountAddress)] = account
-	}
-
-	for _, schedule := range existingSchedules {
-		if sub, ok := activeSubAccounts[schedule.Config.Params.SubAccountAddress]; !ok || sub.Status == 4 {
-			logger.Info(
-				"terminating schedule",
-				log.Str("scheduleID", schedule.ScheduleID),
-				log.Str("subaccount", schedule.Config.Params.SubAccountAddress.Hex()),
-			)
-			if err := s.client.ScheduleClient().GetHandle(ctx, schedule.ScheduleID).Delete(ctx); err != nil {
-				return err
-			}
-		}
-	}
-
-	return nil
-}
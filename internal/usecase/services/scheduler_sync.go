@@ -0,0 +1,616 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Brahma-fi/brahma-builder/internal/entity"
+	"github.com/Brahma-fi/brahma-builder/internal/usecase/workflows"
+	"github.com/Brahma-fi/brahma-builder/pkg/log"
+	"github.com/ethereum/go-ethereum/common"
+	"go.temporal.io/api/enums/v1"
+	"go.temporal.io/api/serviceerror"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/temporal"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	_syncExecutorConcurrency = 4
+	_syncChainConcurrency    = 4
+	_syncScheduleConcurrency = 8
+
+	_subscriptionStatusNotePrefix = "subscription "
+)
+
+// Sync reconciles the schedules of every registered executor with its console subscriptions in process.
+// Failures are isolated to the executor, chain or schedule they happened on and collected in
+// the returned report, the only error returned is the context's.
+func (s *Scheduler) Sync(ctx context.Context) (*entity.SyncReport, error) {
+	startedAt := time.Now()
+	plan, err := s.Plan(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	applied, err := s.Apply(ctx, plan)
+	if err != nil {
+		return nil, err
+	}
+
+	report := plan.Report()
+	report.StartedAt = startedAt
+	report.Merge(applied)
+	report.FinishedAt = time.Now()
+	LogSyncReport(ctx, report)
+	return report, nil
+}
+
+// Plan computes the mutations a sync would apply to bring the schedules in line with the console
+// subscriptions, without applying any of them.
+func (s *Scheduler) Plan(ctx context.Context) (*entity.SyncPlan, error) {
+	plan := &entity.SyncPlan{CreatedAt: time.Now()}
+	snapshot, err := s.FetchSubscriptions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	plan.Failed = snapshot.Failed
+	chainIDs := snapshot.ChainIDs()
+	plan.Chains = make([]entity.ChainSyncPlan, len(chainIDs))
+
+	g := errgroup.Group{}
+	g.SetLimit(_syncChainConcurrency)
+	for i, chainID := range chainIDs {
+		g.Go(func() error {
+			chainPlan, err := s.DiffChain(ctx, chainID, snapshot.ForChain(chainID))
+			if err != nil {
+				return err
+			}
+
+			plan.Chains[i] = *chainPlan
+			return nil
+		})
+	}
+
+	if err = g.Wait(); err != nil {
+		return nil, err
+	}
+
+	return plan, nil
+}
+
+// Apply applies exactly the mutations of plan, see ApplyMutations
+func (s *Scheduler) Apply(ctx context.Context, plan *entity.SyncPlan) (*entity.SyncReport, error) {
+	return s.ApplyMutations(ctx, plan.Mutations())
+}
+
+// SyncTargets returns the executors a sync reconciles, see SyncExecutor
+func (s *Scheduler) SyncTargets(_ context.Context) ([]entity.SyncTarget, error) {
+	executors := s.executors.List()
+	targets := make([]entity.SyncTarget, len(executors))
+	for i, e := range executors {
+		targets[i] = entity.SyncTarget{ExecutorAddress: common.HexToAddress(e.Address), ChainID: e.ChainID}
+	}
+
+	return targets, nil
+}
+
+// SyncExecutor reconciles the schedules of a single executor with its console subscriptions. Subscriptions
+// are fetched page by page, diffed and their mutations applied within the call, so none of them leave it.
+// progress is called after each step and each applied mutation. Calling it again after a partial apply
+// diffs against the schedules as they now are, and only applies what is left.
+func (s *Scheduler) SyncExecutor(
+	ctx context.Context,
+	target entity.SyncTarget,
+	progress func(entity.SyncProgress),
+) (*entity.SyncReport, error) {
+	report := entity.NewSyncReport(time.Now())
+	metadata, subscriptions, err := s.fetchExecutorSubscriptions(ctx, target.ExecutorAddress, target.ChainID)
+	if err != nil {
+		log.GetLogger(ctx).Error(
+			"failed to fetch executor subscriptions",
+			log.Str("executor", target.ExecutorAddress.Hex()),
+			log.Err(err),
+		)
+		report.Fail(entity.SyncReportEntry{
+			Key:    entity.ScheduleKey{ExecutorAddress: target.ExecutorAddress, ChainID: target.ChainID},
+			Reason: err.Error(),
+		})
+		report.FinishedAt = time.Now()
+		return report, ctx.Err()
+	}
+
+	snapshot := &entity.SubscriptionSnapshot{
+		Accounts:         subscriptions,
+		ExecutorMetadata: map[string]*entity.ExecutorMetadata{metadata.Id: metadata},
+		SyncedExecutors:  []common.Address{target.ExecutorAddress},
+	}
+	state := entity.SyncProgress{Subscriptions: len(subscriptions)}
+	progress(state)
+
+	mutations := make([]entity.ScheduleMutation, 0)
+	for _, chainID := range snapshot.ChainIDs() {
+		plan, err := s.DiffChain(ctx, chainID, snapshot.ForChain(chainID))
+		if err != nil {
+			return nil, err
+		}
+
+		report.Merge(plan.Report)
+		mutations = append(mutations, plan.Mutations...)
+		progress(state)
+	}
+
+	state.Mutations = len(mutations)
+	applied, err := s.applyMutations(ctx, mutations, func() {
+		state.Applied++
+		progress(state)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	report.Merge(applied)
+	report.FinishedAt = time.Now()
+	return report, nil
+}
+
+// FetchSubscriptions fetches the subscriptions of every registered executor.
+// Executors which fail to fetch are recorded in the snapshot instead of failing the whole fetch.
+func (s *Scheduler) FetchSubscriptions(ctx context.Context) (*entity.SubscriptionSnapshot, error) {
+	logger := log.GetLogger(ctx)
+	snapshot := &entity.SubscriptionSnapshot{
+		Accounts:         make([]entity.ClientSubscription, 0),
+		ExecutorMetadata: make(map[string]*entity.ExecutorMetadata),
+		SyncedExecutors:  make([]common.Address, 0),
+	}
+
+	var mu sync.Mutex
+	g := errgroup.Group{}
+	g.SetLimit(_syncExecutorConcurrency)
+	for _, e := range s.executors.List() {
+		g.Go(func() error {
+			executor := common.HexToAddress(e.Address)
+			metadata, subscriptions, err := s.fetchExecutorSubscriptions(ctx, executor, e.ChainID)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				logger.Error("failed to fetch executor subscriptions", log.Str("executor", e.Address), log.Err(err))
+				snapshot.Failed = append(snapshot.Failed, entity.SyncReportEntry{
+					Key:    entity.ScheduleKey{ExecutorAddress: executor, ChainID: e.ChainID},
+					Reason: err.Error(),
+				})
+				return nil
+			}
+
+			snapshot.ExecutorMetadata[metadata.Id] = metadata
+			snapshot.SyncedExecutors = append(snapshot.SyncedExecutors, executor)
+			snapshot.Accounts = append(snapshot.Accounts, subscriptions...)
+			return nil
+		})
+	}
+	_ = g.Wait()
+
+	return snapshot, ctx.Err()
+}
+
+func (s *Scheduler) fetchExecutorSubscriptions(
+	ctx context.Context,
+	executor common.Address,
+	chainID int64,
+) (*entity.ExecutorMetadata, []entity.ClientSubscription, error) {
+	metadata, err := s.console.ExecutorByAddressAndChainID(ctx, executor, uint64(chainID))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	subscriptions, err := s.console.Subscriptions(ctx, metadata.Id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return metadata, subscriptions, nil
+}
+
+// DiffChain computes the mutations which bring the chain's schedules in line with the subscriptions
+// of snapshot. Subscriptions which can't be diffed are recorded in the plan's report as skipped or failed.
+func (s *Scheduler) DiffChain(
+	ctx context.Context,
+	chainID int64,
+	snapshot *entity.SubscriptionSnapshot,
+) (*entity.ChainSyncPlan, error) {
+	logger := log.GetLogger(ctx)
+	accounts := snapshot.Accounts
+	logger.Info("diffing chain", log.Int("chainID", int(chainID)), log.Int("accounts", len(accounts)))
+
+	report := entity.NewSyncReport(time.Now())
+	plan := &entity.ChainSyncPlan{ChainID: chainID, Mutations: make([]entity.ScheduleMutation, 0), Report: report}
+	existingSchedules, err := s.schedulesRepo.BySubAccountAddressesChainIDAndStatus(ctx, extractSubAccounts(accounts), chainID)
+	if err != nil {
+		for _, account := range accounts {
+			report.Fail(entity.SyncReportEntry{
+				Key:    subscriptionKey(account, snapshot.ExecutorMetadata, chainID),
+				Reason: fmt.Sprintf("failed to list schedules: %s", err),
+			})
+		}
+		return plan, ctx.Err()
+	}
+
+	existingScheduleMap := createExistingScheduleMap(ctx, existingSchedules)
+	mutations := plan.Mutations
+	seen := make(map[entity.ScheduleKey]bool, len(accounts))
+	for _, account := range accounts {
+		key := subscriptionKey(account, snapshot.ExecutorMetadata, chainID)
+		seen[key] = true
+		existing, exists := existingScheduleMap[key]
+		accountMutations, err := s.diffSubscription(ctx, account, snapshot.ExecutorMetadata, chainID, key, existing, exists)
+		switch {
+		case err != nil:
+			report.Fail(entity.SyncReportEntry{ScheduleID: existing.ScheduleID, Key: key, Reason: err.Error()})
+		case len(accountMutations) == 0 && account.Status.ScheduleAction() == entity.ScheduleActionNone:
+			report.Skip(entity.SyncReportEntry{ScheduleID: existing.ScheduleID, Key: key, Reason: subscriptionStatusNote(account.Status)})
+		default:
+			mutations = append(mutations, accountMutations...)
+		}
+	}
+
+	syncedExecutors := make(map[common.Address]bool, len(snapshot.SyncedExecutors))
+	for _, executor := range snapshot.SyncedExecutors {
+		syncedExecutors[executor] = true
+	}
+
+	for _, schedule := range existingSchedules {
+		key := schedule.Config.Params.Key()
+		if seen[key] {
+			continue
+		}
+
+		entry := entity.SyncReportEntry{ScheduleID: schedule.ScheduleID, Key: key}
+		if !syncedExecutors[key.ExecutorAddress] {
+			entry.Reason = "subscriptions of executor were not part of the sync"
+			report.Skip(entry)
+			continue
+		}
+
+		mutations = append(mutations, entity.ScheduleMutation{
+			Type:       entity.ScheduleMutationDelete,
+			ScheduleID: schedule.ScheduleID,
+			Key:        key,
+			Reason:     "subscription not found",
+		})
+	}
+
+	plan.Mutations = mutations
+	report.FinishedAt = time.Now()
+	return plan, nil
+}
+
+// diffSubscription returns the mutations of a single subscription's schedule, in the order they must be applied
+func (s *Scheduler) diffSubscription(
+	ctx context.Context,
+	account entity.ClientSubscription,
+	executorMetadata map[string]*entity.ExecutorMetadata,
+	chainID int64,
+	key entity.ScheduleKey,
+	existing entity.Schedule,
+	exists bool,
+) ([]entity.ScheduleMutation, error) {
+	note := subscriptionStatusNote(account.Status)
+	action := account.Status.ScheduleAction()
+	switch {
+	case action == entity.ScheduleActionNone:
+		if !account.Status.Known() {
+			log.GetLogger(ctx).Warn(
+				"unknown subscription status, leaving its schedule as is",
+				log.Str("subscriptionID", account.Id),
+				log.Str("subaccount", account.SubAccountAddress),
+				log.Str("status", account.Status.String()),
+			)
+		}
+		return nil, nil
+	case action == entity.ScheduleActionDelete:
+		if !exists {
+			return nil, nil
+		}
+
+		return []entity.ScheduleMutation{{
+			Type:       entity.ScheduleMutationDelete,
+			ScheduleID: existing.ScheduleID,
+			Key:        key,
+			Reason:     note,
+		}}, nil
+	case action == entity.ScheduleActionPause && !exists:
+		return nil, nil
+	}
+
+	metadata, ok := executorMetadata[account.RegistryId]
+	if !ok {
+		return nil, fmt.Errorf("executor metadata not found for registry %s", account.RegistryId)
+	}
+
+	config, err := s.createWorkflowConfig(account, metadata, chainID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !exists {
+		config.Schedule.ID = config.Params.ID()
+		return []entity.ScheduleMutation{{
+			Type:       entity.ScheduleMutationCreate,
+			ScheduleID: config.Schedule.ID,
+			Key:        key,
+			Config:     &config,
+			Reason:     note,
+		}}, nil
+	}
+
+	mutations := make([]entity.ScheduleMutation, 0)
+	// keep targeting the schedule that already exists for this subscription
+	config.Schedule.ID = existing.ScheduleID
+	hash, err := config.Hash()
+	if err != nil {
+		return nil, err
+	}
+
+	if hash != existing.ConfigHash {
+		mutations = append(mutations, entity.ScheduleMutation{
+			Type:       entity.ScheduleMutationUpdate,
+			ScheduleID: existing.ScheduleID,
+			Key:        key,
+			Config:     &config,
+			Reason:     fmt.Sprintf("config hash changed from %q to %q", existing.ConfigHash, hash),
+		})
+	}
+
+	switch {
+	case action == entity.ScheduleActionPause && !existing.Paused:
+		mutations = append(mutations, entity.ScheduleMutation{
+			Type:       entity.ScheduleMutationPause,
+			ScheduleID: existing.ScheduleID,
+			Key:        key,
+			Reason:     note,
+		})
+	// only schedules paused by the scheduler are unpaused, anything paused by an operator or by
+	// Temporal itself (pause on failure) is left paused
+	case action == entity.ScheduleActionRun && existing.Paused && strings.HasPrefix(existing.Note, _subscriptionStatusNotePrefix):
+		mutations = append(mutations, entity.ScheduleMutation{
+			Type:       entity.ScheduleMutationUnpause,
+			ScheduleID: existing.ScheduleID,
+			Key:        key,
+			Reason:     note,
+		})
+	}
+
+	return mutations, nil
+}
+
+// ApplyMutations applies mutations concurrently across schedules and in order within a schedule.
+// A mutation failing is recorded in the returned report, the only error returned is the context's.
+func (s *Scheduler) ApplyMutations(
+	ctx context.Context,
+	mutations []entity.ScheduleMutation,
+) (*entity.SyncReport, error) {
+	return s.applyMutations(ctx, mutations, func() {})
+}
+
+// applyMutations is ApplyMutations calling onApplied after each mutation it attempted
+func (s *Scheduler) applyMutations(
+	ctx context.Context,
+	mutations []entity.ScheduleMutation,
+	onApplied func(),
+) (*entity.SyncReport, error) {
+	report := entity.NewSyncReport(time.Now())
+	bySchedule := make(map[string][]entity.ScheduleMutation)
+	order := make([]string, 0)
+	for _, mutation := range mutations {
+		if _, ok := bySchedule[mutation.ScheduleID]; !ok {
+			order = append(order, mutation.ScheduleID)
+		}
+		bySchedule[mutation.ScheduleID] = append(bySchedule[mutation.ScheduleID], mutation)
+	}
+
+	var mu sync.Mutex
+	g := errgroup.Group{}
+	g.SetLimit(_syncScheduleConcurrency)
+	for _, scheduleID := range order {
+		g.Go(func() error {
+			for _, mutation := range bySchedule[scheduleID] {
+				err := s.applyMutation(ctx, mutation)
+
+				mu.Lock()
+				report.Applied(mutation, err)
+				onApplied()
+				mu.Unlock()
+				if err != nil {
+					// later mutations of the schedule depend on this one
+					break
+				}
+			}
+			return nil
+		})
+	}
+	_ = g.Wait()
+
+	report.FinishedAt = time.Now()
+	return report, ctx.Err()
+}
+
+func (s *Scheduler) applyMutation(ctx context.Context, mutation entity.ScheduleMutation) error {
+	logger := log.GetLogger(ctx)
+	logger.Info(
+		"applying schedule mutation",
+		log.Str("type", string(mutation.Type)),
+		log.Str("scheduleID", mutation.ScheduleID),
+		log.Str("subaccount", mutation.Key.SubAccountAddress.Hex()),
+		log.Str("subscriptionID", mutation.Key.SubscriptionID),
+		log.Str("reason", mutation.Reason),
+	)
+
+	handle := s.client.ScheduleClient().GetHandle(ctx, mutation.ScheduleID)
+	var err error
+	switch mutation.Type {
+	case entity.ScheduleMutationCreate:
+		_, err = s.create(ctx, *mutation.Config, true, false, "")
+	case entity.ScheduleMutationUpdate:
+		err = s.Update(ctx, *mutation.Config)
+	case entity.ScheduleMutationPause:
+		err = handle.Pause(ctx, client.SchedulePauseOptions{Note: mutation.Reason})
+	case entity.ScheduleMutationUnpause:
+		err = handle.Unpause(ctx, client.ScheduleUnpauseOptions{Note: mutation.Reason})
+	case entity.ScheduleMutationDelete:
+		err = handle.Delete(ctx)
+	default:
+		err = fmt.Errorf("unknown mutation %s", mutation.Type)
+	}
+
+	if mutationApplied(mutation.Type, err) {
+		// a previous attempt applied it before failing on a later mutation
+		logger.Info(
+			"schedule mutation already applied",
+			log.Str("type", string(mutation.Type)),
+			log.Str("scheduleID", mutation.ScheduleID),
+		)
+		return nil
+	}
+
+	if err != nil {
+		logger.Error(
+			"failed to apply schedule mutation",
+			log.Str("type", string(mutation.Type)),
+			log.Str("scheduleID", mutation.ScheduleID),
+			log.Err(err),
+		)
+	}
+
+	return err
+}
+
+// mutationApplied is true when mutation failed with err only because it had already been applied
+func mutationApplied(mutation entity.ScheduleMutationType, err error) bool {
+	var notFound *serviceerror.NotFound
+	switch mutation {
+	case entity.ScheduleMutationCreate:
+		return errors.Is(err, temporal.ErrScheduleAlreadyRunning)
+	case entity.ScheduleMutationDelete, entity.ScheduleMutationPause, entity.ScheduleMutationUnpause:
+		return errors.As(err, &notFound)
+	default:
+		return false
+	}
+}
+
+// LogSyncReport logs the outcome counts of report, along with the reason of every failure
+func LogSyncReport(ctx context.Context, report *entity.SyncReport) {
+	logger := log.GetLogger(ctx)
+	fields := []log.Field{
+		log.Int("created", len(report.Created)),
+		log.Int("updated", len(report.Updated)),
+		log.Int("paused", len(report.Paused)),
+		log.Int("unpaused", len(report.Unpaused)),
+		log.Int("deleted", len(report.Deleted)),
+		log.Int("skipped", len(report.Skipped)),
+		log.Int("failed", len(report.Failed)),
+		log.Str("took", report.FinishedAt.Sub(report.StartedAt).String()),
+	}
+
+	if len(report.Failed) != 0 {
+		logger.Warn("sync finished with failures", append(fields, log.Any("failures", report.Failed))...)
+		return
+	}
+
+	logger.Info("sync finished", fields...)
+}
+
+func extractSubAccounts(accounts []entity.ClientSubscription) []common.Address {
+	subAccounts := make([]common.Address, len(accounts))
+	for i, account := range accounts {
+		subAccounts[i] = common.HexToAddress(account.SubAccountAddress)
+	}
+	return subAccounts
+}
+
+// subscriptionKey is the key of the schedule which runs the subscription
+func subscriptionKey(
+	account entity.ClientSubscription,
+	executorMetadata map[string]*entity.ExecutorMetadata,
+	chainID int64,
+) entity.ScheduleKey {
+	key := entity.ScheduleKey{
+		SubAccountAddress: common.HexToAddress(account.SubAccountAddress),
+		ChainID:           chainID,
+		SubscriptionID:    account.Id,
+	}
+	if metadata, ok := executorMetadata[account.RegistryId]; ok {
+		key.ExecutorAddress = common.HexToAddress(metadata.Executor)
+	}
+
+	return key
+}
+
+func createExistingScheduleMap(ctx context.Context, existingSchedules []entity.Schedule) map[entity.ScheduleKey]entity.Schedule {
+	logger := log.GetLogger(ctx)
+	existingScheduleMap := make(map[entity.ScheduleKey]entity.Schedule)
+	for _, schedule := range existingSchedules {
+		logger.Debug(
+			"existing schedule",
+			log.Str("id", schedule.ScheduleID),
+			log.Str("subacc", schedule.Config.Params.SubAccountAddress.Hex()),
+			log.Str("subscriptionID", schedule.Config.Params.Subscription.Id),
+		)
+		existingScheduleMap[schedule.Config.Params.Key()] = schedule
+	}
+	return existingScheduleMap
+}
+
+func subscriptionStatusNote(status entity.SubscriptionStatus) string {
+	return _subscriptionStatusNotePrefix + status.String()
+}
+
+// EnsureSyncSchedule creates the schedule running the subscription sync workflow every interval,
+// or moves an existing one to interval. Temporal runs at most one sync at a time across replicas.
+func (s *Scheduler) EnsureSyncSchedule(ctx context.Context, every time.Duration) error {
+	logger := log.GetLogger(ctx)
+	spec := client.ScheduleSpec{Intervals: []client.ScheduleIntervalSpec{{Every: every}}}
+	_, err := s.client.ScheduleClient().Create(ctx, client.ScheduleOptions{
+		ID:                 entity.SyncScheduleID,
+		Spec:               spec,
+		Action:             syncScheduleAction(),
+		Overlap:            enums.SCHEDULE_OVERLAP_POLICY_SKIP,
+		TriggerImmediately: true,
+	})
+	switch {
+	case err == nil:
+		logger.Info("created sync schedule", log.Str("every", every.String()))
+		return nil
+	case !errors.Is(err, temporal.ErrScheduleAlreadyRunning):
+		return fmt.Errorf("failed to create sync schedule: %w", err)
+	}
+
+	return s.client.ScheduleClient().GetHandle(ctx, entity.SyncScheduleID).Update(ctx, client.ScheduleUpdateOptions{
+		DoUpdate: func(input client.ScheduleUpdateInput) (*client.ScheduleUpdate, error) {
+			schedule := input.Description.Schedule
+			if schedule.Spec != nil && reflect.DeepEqual(schedule.Spec.Intervals, spec.Intervals) {
+				return nil, temporal.ErrSkipScheduleUpdate
+			}
+
+			logger.Info("updating sync schedule", log.Str("every", every.String()))
+			schedule.Spec = &spec
+			schedule.Action = syncScheduleAction()
+			return &client.ScheduleUpdate{Schedule: &schedule}, nil
+		},
+	})
+}
+
+func syncScheduleAction() *client.ScheduleWorkflowAction {
+	w := workflows.SubscriptionSync{}
+	return &client.ScheduleWorkflowAction{
+		ID:        entity.SyncScheduleID,
+		TaskQueue: entity.BaseTaskQueue,
+		Workflow:  w.SyncWorkflow,
+	}
+}
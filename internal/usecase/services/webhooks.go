@@ -0,0 +1,60 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+const WebhookTaskPath = "/v1/webhooks/tasks"
+
+// Webhooks signs and verifies the per-execution callbacks handed to the console.
+// The token is the last segment of the callback URL, the console calls the URL as given.
+// A token is bound to a single workflow run, so a leaked token can only signal that run.
+type Webhooks struct {
+	baseURL string
+	secret  []byte
+}
+
+func NewWebhooks(baseURL, secret string) *Webhooks {
+	return &Webhooks{baseURL: strings.TrimSuffix(baseURL, "/"), secret: []byte(secret)}
+}
+
+// Enabled is true when both the public URL and the secret of the webhook server are configured
+func (w *Webhooks) Enabled() bool {
+	return w.baseURL != "" && len(w.secret) != 0
+}
+
+// TaskURL returns the signed task callback URL of a workflow run, empty when webhooks aren't configured
+func (w *Webhooks) TaskURL(workflowID, runID string) string {
+	if !w.Enabled() {
+		return ""
+	}
+
+	return fmt.Sprintf(
+		"%s%s/%s/%s/%s",
+		w.baseURL,
+		WebhookTaskPath,
+		url.PathEscape(workflowID),
+		url.PathEscape(runID),
+		w.token(workflowID, runID),
+	)
+}
+
+// Verify checks token was issued by TaskURL for the workflow run
+func (w *Webhooks) Verify(workflowID, runID, token string) bool {
+	if len(w.secret) == 0 {
+		return false
+	}
+
+	return hmac.Equal([]byte(token), []byte(w.token(workflowID, runID)))
+}
+
+func (w *Webhooks) token(workflowID, runID string) string {
+	mac := hmac.New(sha256.New, w.secret)
+	mac.Write([]byte(workflowID + "/" + runID))
+	return hex.EncodeToString(mac.Sum(nil))
+}
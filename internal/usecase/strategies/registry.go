@@ -0,0 +1,136 @@
+package strategies
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/Brahma-fi/brahma-builder/internal/entity"
+	"github.com/Brahma-fi/brahma-builder/pkg/temporal"
+	sdktemporal "go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+)
+
+const _errTypeInvalidStrategyParams = "InvalidStrategyParams"
+
+// LegacyActivityName is the activity every strategy was registered as before each had its own ActivityName.
+// Runs started before then still schedule it, see Definition.LegacyActivity.
+const LegacyActivityName = "ExecutionHandler"
+
+// Input is what a strategy activity executes with, Params being decoded from the subscription metadata
+type Input[P any] struct {
+	entity.ExecCtx
+	Params P `json:"params"`
+}
+
+// Result is a strategy's typed output, which always carries the execution result the orchestrator acts on
+type Result interface {
+	Outcome() entity.ExecutionResult
+}
+
+// Handler is the activity implementing a strategy
+type Handler[P any, R Result] func(ctx context.Context, input Input[P]) (R, error)
+
+// Strategy is a registered strategy with its params and result types erased, as resolved by the orchestrator
+type Strategy interface {
+	StrategyID() string
+	Name() string
+	// Execute runs the strategy's activity from a workflow
+	Execute(ctx workflow.Context, execCtx entity.ExecCtx) (entity.ExecutionResult, error)
+}
+
+// Definition declares a strategy: the ExecutorConfig.ID it runs for, the name its activity is registered
+// under, and through its type parameters the params and result of that activity.
+type Definition[P any, R Result] struct {
+	ID           string
+	ActivityName string
+}
+
+func (d Definition[P, R]) StrategyID() string {
+	return d.ID
+}
+
+func (d Definition[P, R]) Name() string {
+	return d.ActivityName
+}
+
+func (d Definition[P, R]) Execute(ctx workflow.Context, execCtx entity.ExecCtx) (entity.ExecutionResult, error) {
+	input, err := d.input(execCtx)
+	if err != nil {
+		return entity.ExecutionResult{}, err
+	}
+
+	var result R
+	if err := workflow.ExecuteActivity(ctx, d.ActivityName, input).Get(ctx, &result); err != nil {
+		return entity.ExecutionResult{}, err
+	}
+
+	return result.Outcome(), nil
+}
+
+// Activity binds handler to the strategy's activity name, the compiler checking it takes P and returns R
+func (d Definition[P, R]) Activity(handler Handler[P, R]) temporal.NamedActivity {
+	return temporal.NamedActivity{Name: d.ActivityName, Func: handler}
+}
+
+// LegacyActivity binds handler to LegacyActivityName. Runs started before strategies had their own activity
+// name schedule it with the bare ExecCtx, the params are then decoded from the subscription by the activity.
+// Keep registering it until no such run is left in retention.
+func (d Definition[P, R]) LegacyActivity(handler Handler[P, R]) temporal.NamedActivity {
+	return temporal.NamedActivity{
+		Name: LegacyActivityName,
+		Func: func(ctx context.Context, execCtx entity.ExecCtx) (R, error) {
+			input, err := d.input(execCtx)
+			if err != nil {
+				var result R
+				return result, err
+			}
+
+			return handler(ctx, input)
+		},
+	}
+}
+
+// input decodes the strategy's params from the subscription metadata of execCtx
+func (d Definition[P, R]) input(execCtx entity.ExecCtx) (Input[P], error) {
+	input := Input[P]{ExecCtx: execCtx}
+	if len(execCtx.Params.Subscription.Metadata) == 0 {
+		return input, nil
+	}
+
+	if err := json.Unmarshal(execCtx.Params.Subscription.Metadata, &input.Params); err != nil {
+		return input, sdktemporal.NewNonRetryableApplicationError(
+			fmt.Sprintf("failed to decode %s params: %s", d.ID, err),
+			_errTypeInvalidStrategyParams,
+			err,
+		)
+	}
+
+	return input, nil
+}
+
+// Registry resolves the strategy of an executor by its ExecutorConfig.ID
+type Registry struct {
+	strategies map[string]Strategy
+}
+
+func NewRegistry(strategies ...Strategy) (*Registry, error) {
+	registry := &Registry{strategies: make(map[string]Strategy, len(strategies))}
+	for _, s := range strategies {
+		if _, ok := registry.strategies[s.StrategyID()]; ok {
+			return nil, fmt.Errorf("strategy %s registered twice", s.StrategyID())
+		}
+		registry.strategies[s.StrategyID()] = s
+	}
+
+	return registry, nil
+}
+
+func (r *Registry) ByID(id string) (Strategy, error) {
+	s, ok := r.strategies[id]
+	if !ok {
+		return nil, fmt.Errorf("strategy not found: %s", id)
+	}
+
+	return s, nil
+}
@@ -1,9 +1,35 @@
 package activities
 
 import (
+	"context"
+
+	"github.com/Brahma-fi/brahma-builder/internal/entity"
 	"github.com/Brahma-fi/brahma-builder/pkg/rpc"
+	"github.com/google/uuid"
+	"go.temporal.io/sdk/client"
 )
 
 type rpcFactory interface {
 	RetryableClient(chainID int64) (*rpc.Clients, error)
 }
+
+type taskStatusReader interface {
+	TaskStatus(ctx context.Context, taskID string) (*entity.TaskStatus, error)
+}
+
+type executionsLog interface {
+	LatestBySubID(ctx context.Context, subID uuid.UUID) (*entity.Log, error)
+	Insert(ctx context.Context, log *entity.Log) error
+}
+
+type workflowSignaler interface {
+	SignalWithStartWorkflow(
+		ctx context.Context,
+		workflowID string,
+		signalName string,
+		signalArg interface{},
+		options client.StartWorkflowOptions,
+		workflow interface{},
+		workflowArgs ...interface{},
+	) (client.WorkflowRun, error)
+}
@@ -0,0 +1,51 @@
+package activities
+
+import (
+	"context"
+	"time"
+
+	"github.com/Brahma-fi/brahma-builder/internal/entity"
+	"github.com/Brahma-fi/brahma-builder/internal/usecase/workflows"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+)
+
+// LockActivity queues lock requests on sub-account mutexes, starting the mutex workflow when none runs
+type LockActivity struct {
+	client workflowSignaler
+}
+
+func NewLockActivity(client workflowSignaler) *LockActivity {
+	return &LockActivity{client: client}
+}
+
+// RequestLock queues req on the mutexID workflow, the lock is granted to the owner with SignalLockGranted
+func (l *LockActivity) RequestLock(ctx context.Context, mutexID string, req entity.LockRequest) error {
+	m := workflows.SubAccountMutex{}
+	_, err := l.client.SignalWithStartWorkflow(
+		ctx,
+		mutexID,
+		entity.SignalLockAcquire,
+		req,
+		client.StartWorkflowOptions{
+			ID:        mutexID,
+			TaskQueue: entity.BaseTaskQueue,
+		},
+		m.MutexWorkflow,
+		[]entity.LockRequest(nil),
+	)
+	return err
+}
+
+func (l *LockActivity) Options() workflow.ActivityOptions {
+	return workflow.ActivityOptions{
+		TaskQueue: entity.BaseTaskQueue,
+		RetryPolicy: &temporal.RetryPolicy{
+			MaximumInterval:        time.Second * 30,
+			MaximumAttempts:        5,
+			NonRetryableErrorTypes: []string{},
+		},
+		StartToCloseTimeout: time.Minute,
+	}
+}
@@ -3,18 +3,19 @@ package morpho
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"math/big"
 	"slices"
 	"strings"
 
 	"github.com/Brahma-fi/brahma-builder/internal/entity"
+	"github.com/Brahma-fi/brahma-builder/internal/usecase/strategies"
 	utils "github.com/Brahma-fi/brahma-builder/pkg/utils/abis/erc20"
 	"github.com/Brahma-fi/go-safe/encoders"
 	safetypes "github.com/Brahma-fi/go-safe/types"
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common/hexutil"
-	"github.com/google/uuid"
 	"go.temporal.io/sdk/activity"
 	"go.temporal.io/sdk/log"
 
@@ -26,66 +27,134 @@ type ReBalancingStrategy struct {
 	client         morphoClient
 	executor       consoleExecutor
 	config         *Config
-	logsRepo       executionsLogRepo
 	bundlerAddress common.Address
 	caller         bind.ContractCaller
 	oracle         pricingOracle
+	gas            gasOracle
+	pluginAddress  common.Address
 }
 
 func NewReBalancingStrategy(
 	client morphoClient,
 	executor consoleExecutor,
 	caller bind.ContractCaller,
-	logsRepo executionsLogRepo,
 	config *Config,
 	oracle pricingOracle,
+	gas gasOracle,
+	pluginAddress common.Address,
 ) (*ReBalancingStrategy, error) {
+	if config.GasFee != nil && config.GasFee.NativeToken == "" {
+		return nil, errors.New("gas fee needs a native token to price gas with")
+	}
+
+	if _, _, err := config.Rebalance.durations(); err != nil {
+		return nil, fmt.Errorf("invalid rebalance policy: %w", err)
+	}
+
+	if err := config.Allocation.validate(); err != nil {
+		return nil, fmt.Errorf("invalid allocation: %w", err)
+	}
+
 	return &ReBalancingStrategy{
 		client:         client,
 		caller:         caller,
 		executor:       executor,
 		config:         config,
-		logsRepo:       logsRepo,
 		bundlerAddress: common.HexToAddress(config.BundlerAddress),
 		oracle:         oracle,
+		gas:            gas,
+		pluginAddress:  pluginAddress,
 	}, nil
 }
 
 func (m *ReBalancingStrategy) ExecutionHandler(
 	ctx context.Context,
-	execCtx entity.ExecCtx,
-) error {
+	input strategies.Input[StrategyParams],
+) (Result, error) {
 	logger := activity.GetLogger(ctx)
-	params := &StrategyParams{}
-	if err := json.Unmarshal(execCtx.Params.Subscription.Metadata, params); err != nil {
-		return fmt.Errorf("failed to unmarshal strategy params: %w", err)
-	}
+	execCtx, params := input.ExecCtx, &input.Params
 
 	initialState, err := m.getInitialState(ctx, execCtx, params, execCtx.Params.ChainID)
 	if err != nil {
-		return fmt.Errorf("failed to get initial state: %w", err)
+		return Result{}, fmt.Errorf("failed to get initial state: %w", err)
+	}
+
+	if m.config.Allocation.Mode == AllocationSplit {
+		return m.allocate(ctx, logger, execCtx, params, initialState)
 	}
 
-	bestVault, _ := m.findBestVault(initialState.vaults, initialState.minUnderlyingLiquidity)
+	bestVault, bestApy := m.findBestVault(initialState.vaults, initialState.minUnderlyingLiquidity)
 
 	if bestVault == initialState.currentVault {
 		logger.Info("No re-balance signal")
-		return nil
+		return noAction("No re-balance signal", fmt.Sprintf("already in best vault %s", bestVault.Hex())), nil
 	}
 
 	if len(m.config.WhitelistedVaults) != 0 && !slices.Contains(m.config.WhitelistedVaults, bestVault.Hex()) {
-		return fmt.Errorf("vault not whitelisted %s", bestVault.Hex())
+		return Result{}, fmt.Errorf("vault not whitelisted %s", bestVault.Hex())
 	}
 
-	if !initialState.isAlreadyInVault && initialState.hasAvailableBalance {
-		return m.handleDeposit(ctx, logger, execCtx, initialState.subaccount, bestVault, params, int64(execCtx.Params.Subscription.ChainId))
+	var (
+		executionLog *ExecutionLog
+		action       entity.ExecutionAction
+		reason       string
+	)
+	switch {
+	case !initialState.isAlreadyInVault && initialState.hasAvailableBalance:
+		action = ActionDeposit
+		reason = fmt.Sprintf("idle balance, best vault %s at %.4f apy", bestVault.Hex(), bestApy)
+		executionLog, err = m.handleDeposit(ctx, logger, execCtx, initialState.subaccount, bestVault, params, int64(execCtx.Params.Subscription.ChainId))
+	case initialState.isAlreadyInVault && bestVault != initialState.currentVault:
+		var hold string
+		policy := m.config.Rebalance.merge(params.Rebalance)
+		hold, err = policy.holdReason(execCtx, initialState.vaults, initialState.currentVault, bestApy)
+		if err != nil {
+			return Result{}, fmt.Errorf("invalid rebalance policy: %w", err)
+		}
+		if hold != "" {
+			logger.Info("Holding position", "vault", initialState.currentVault.Hex(), "best", bestVault.Hex(), "reason", hold)
+			return noAction("Rebalance not worth it", hold), nil
+		}
+
+		currentApy, _ := vaultApy(initialState.vaults, initialState.currentVault)
+		action = ActionRebalance
+		reason = fmt.Sprintf("vault %s at %.4f apy beats %s at %.4f", bestVault.Hex(), bestApy, initialState.currentVault.Hex(), currentApy)
+		executionLog, err = m.handleRebalance(ctx, logger, execCtx, initialState.subaccount, initialState.currentVault, bestVault, int64(execCtx.Params.Subscription.ChainId), params, policy, bestApy-currentApy)
+		if errors.Is(err, errRebalanceNotWorth) {
+			logger.Info("Holding position", "vault", initialState.currentVault.Hex(), "best", bestVault.Hex(), "reason", err.Error())
+			return noAction("Rebalance not worth it", err.Error()), nil
+		}
+	default:
+		return noAction("Nothing to execute", "no balance to deposit"), nil
+	}
+	if err != nil {
+		return Result{}, err
 	}
 
-	if initialState.isAlreadyInVault && bestVault != initialState.currentVault {
-		return m.handleRebalance(ctx, logger, execCtx, initialState.subaccount, initialState.currentVault, bestVault, int64(execCtx.Params.Subscription.ChainId), params)
+	state, err := json.Marshal(executionLog.Metadata)
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to marshal execution metadata: %w", err)
 	}
 
-	return nil
+	return Result{
+		ExecutionResult: entity.ExecutionResult{
+			Action:  action,
+			TaskID:  executionLog.Metadata.TaskID,
+			Amounts: executionLog.Metadata.TransitionState.Current.Amounts(),
+			Reason:  reason,
+			Message: executionLog.Message,
+			State:   state,
+		},
+		TransitionState: &executionLog.Metadata.TransitionState,
+	}, nil
+}
+
+func noAction(message, reason string) Result {
+	return Result{ExecutionResult: entity.ExecutionResult{
+		Action:  entity.ExecutionActionNone,
+		Reason:  reason,
+		Message: message,
+	}}
 }
 
 type State struct {
@@ -163,14 +232,14 @@ func (m *ReBalancingStrategy) handleDeposit(
 	subaccount, bestVault common.Address,
 	params *StrategyParams,
 	chainID int64,
-) error {
+) (*ExecutionLog, error) {
 	logger.Info("Entering strategy", "address", bestVault.String())
-	_, err := m.Deposit(ctx, logger, subaccount, bestVault, params, chainID)
+	executionLog, err := m.Deposit(ctx, logger, subaccount, bestVault, params, chainID)
 	if err != nil {
-		return fmt.Errorf("failed to deposit: %w", err)
+		return nil, fmt.Errorf("failed to deposit: %w", err)
 	}
 
-	return nil
+	return executionLog, nil
 }
 
 func (m *ReBalancingStrategy) handleRebalance(
@@ -180,19 +249,19 @@ func (m *ReBalancingStrategy) handleRebalance(
 	subaccount, currentVault, bestVault common.Address,
 	chainID int64,
 	params *StrategyParams,
-) error {
+	policy RebalancePolicy,
+	apyDelta float64,
+) (*ExecutionLog, error) {
 	logger.Info("Re-balance strategy", "from", currentVault.String(), "to", bestVault.String())
-	subID, err := uuid.Parse(execCtx.Params.Subscription.Id)
-	if err != nil {
-		return fmt.Errorf("failed to parse subscription ID: %w", err)
-	}
-
-	_, err = m.RedeemAndDeposit(ctx, logger, subID, subaccount, currentVault, bestVault, chainID, params)
-	if err != nil {
-		return fmt.Errorf("failed to redeem and deposit: %w", err)
+	executionLog, err := m.RedeemAndDeposit(ctx, logger, execCtx.PrevState, subaccount, currentVault, bestVault, chainID, params, policy, apyDelta)
+	switch {
+	case errors.Is(err, errRebalanceNotWorth):
+		return nil, err
+	case err != nil:
+		return nil, fmt.Errorf("failed to redeem and deposit: %w", err)
 	}
 
-	return nil
+	return executionLog, nil
 }
 
 func (m *ReBalancingStrategy) Deposit(
@@ -202,41 +271,31 @@ func (m *ReBalancingStrategy) Deposit(
 	params *StrategyParams,
 	chainID int64,
 ) (*ExecutionLog, error) {
-	_, depositAmount, baseFees, err := m.calculateDepositAmountsAndFees(ctx, user, params, chainID)
+	balance, err := m.getSubAccountBalance(ctx, params.BaseToken, user)
 	if err != nil {
 		return nil, err
 	}
 
-	transactions, err := m.prepareDepositTransactions(ctx, user, vault, depositAmount, baseFees, params)
-	if err != nil {
-		return nil, err
+	build := func(ctx context.Context, baseFees *big.Int) ([]safetypes.Transaction, error) {
+		if err := m.validateBalance(balance, baseFees); err != nil {
+			return nil, err
+		}
+		return m.prepareDepositTransactions(ctx, user, vault, new(big.Int).Sub(balance, baseFees), baseFees, params)
 	}
 
-	return m.executeDeposit(ctx, logger, user, vault, depositAmount, baseFees, transactions, chainID)
-}
-
-func (m *ReBalancingStrategy) calculateDepositAmountsAndFees(
-	ctx context.Context,
-	user common.Address,
-	params *StrategyParams,
-	chainID int64,
-) (*big.Int, *big.Int, *big.Int, error) {
-	balance, err := m.getSubAccountBalance(ctx, params.BaseToken, user)
+	baseFees, fees, err := m.calculateBaseFee(ctx, user, params.BaseToken, chainID, ActionDeposit, build)
 	if err != nil {
-		return nil, nil, nil, err
+		return nil, err
 	}
 
-	baseFeeAmt, err := m.calculateBaseFee(ctx, params.BaseToken, chainID)
+	transactions, err := build(ctx, baseFees)
 	if err != nil {
-		return nil, nil, nil, err
-	}
-
-	if err = m.validateBalance(balance, baseFeeAmt); err != nil {
-		return nil, nil, nil, err
+		return nil, err
 	}
 
-	depositAmount := new(big.Int).Sub(balance, baseFeeAmt)
-	return balance, depositAmount, baseFeeAmt, nil
+	depositAmount := new(big.Int).Sub(balance, baseFees)
+	fees.YieldFee = "0"
+	return m.executeDeposit(ctx, logger, user, vault, depositAmount, baseFees, fees, transactions, chainID)
 }
 
 func (m *ReBalancingStrategy) prepareDepositTransactions(
@@ -268,6 +327,7 @@ func (m *ReBalancingStrategy) executeDeposit(
 	logger log.Logger,
 	user, vault common.Address,
 	depositAmount, baseFees *big.Int,
+	fees *FeeBreakdown,
 	transactions []safetypes.Transaction,
 	chainID int64,
 ) (*ExecutionLog, error) {
@@ -308,6 +368,7 @@ func (m *ReBalancingStrategy) executeDeposit(
 					InputAmount:    depositAmount.String(),
 					FeesAmount:     baseFees.String(),
 					GeneratedYield: "0",
+					Fees:           fees,
 				},
 				Prev: nil,
 			},
@@ -318,74 +379,85 @@ func (m *ReBalancingStrategy) executeDeposit(
 func (m *ReBalancingStrategy) RedeemAndDeposit(
 	ctx context.Context,
 	logger log.Logger,
-	subID uuid.UUID,
+	prevState *entity.ExecutionState,
 	user, from, to common.Address,
 	chainID int64,
 	params *StrategyParams,
+	policy RebalancePolicy,
+	apyDelta float64,
 ) (*ExecutionLog, error) {
-	latest, err := m.logsRepo.LatestBySubID(ctx, subID)
+	balance, err := m.client.PreviewRedeem(ctx, from, user)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get latest logs: %w", err)
+		return nil, fmt.Errorf("failed to preview redeem: %w", err)
 	}
 
-	metadata := &ExecutionMetadata{}
-	if err = json.Unmarshal(latest.Metadata.([]uint8), metadata); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal metadata: %w", err)
+	yield, prev, err := m.yieldSince(logger, prevState, balance)
+	if err != nil {
+		return nil, err
 	}
 
-	balance, err := m.client.PreviewRedeem(ctx, from, user)
+	build := func(ctx context.Context, baseFees *big.Int) ([]safetypes.Transaction, error) {
+		transactions, _, _, err := m.prepareRedeemAndDepositTransactions(ctx, user, from, to, balance, baseFees, yield, params)
+		return transactions, err
+	}
+
+	baseFeeAmt, fees, err := m.calculateBaseFee(ctx, user, params.BaseToken, chainID, ActionRebalance, build)
 	if err != nil {
-		return nil, fmt.Errorf("failed to preview redeem: %w", err)
+		return nil, err
+	}
+
+	if err = policy.checkGain(balance, apyDelta, baseFeeAmt); err != nil {
+		return nil, err
 	}
 
-	transactions, depositAmount, baseFees, yield, err := m.prepareRedeemAndDepositTransactions(
+	transactions, depositAmount, baseFees, err := m.prepareRedeemAndDepositTransactions(
 		ctx,
 		user,
 		from,
 		to,
 		balance,
-		metadata,
+		baseFeeAmt,
+		yield,
 		params,
-		chainID,
 	)
 	if err != nil {
 		return nil, err
 	}
 
-	return m.executeRedeemAndDeposit(ctx, logger, user, from, to, depositAmount, baseFees, yield, transactions, metadata.TransitionState.Current, chainID)
+	fees.YieldFee = new(big.Int).Sub(baseFees, baseFeeAmt).String()
+	return m.executeRedeemAndDeposit(ctx, logger, user, from, to, depositAmount, baseFees, yield, fees, transactions, prev, chainID)
 }
 
+// prepareRedeemAndDepositTransactions charges baseFeeAmt and the yield fees, and deposits the rest of balance
 func (m *ReBalancingStrategy) prepareRedeemAndDepositTransactions(
 	ctx context.Context,
 	user, from, to common.Address,
-	balance *big.Int,
-	metadata *ExecutionMetadata,
+	balance, baseFeeAmt, yield *big.Int,
 	params *StrategyParams,
-	chainID int64,
-) ([]safetypes.Transaction, *big.Int, *big.Int, *big.Int, error) {
+) ([]safetypes.Transaction, *big.Int, *big.Int, error) {
 	redeemTxn, err := m.prepareRedeemTxn(ctx, from, user)
 	if err != nil {
-		return nil, nil, nil, nil, err
+		return nil, nil, nil, err
 	}
 
-	baseFees, depositAmount, yield, err := m.calculateRedeemAndDepositAmounts(ctx, balance, metadata, params, chainID)
+	baseFees, depositAmount, err := m.calculateRedeemAndDepositAmounts(balance, baseFeeAmt, yield)
 	if err != nil {
-		return nil, nil, nil, nil, err
+		return nil, nil, nil, err
 	}
 
 	approveTxn, err := m.prepareApproveTxn(depositAmount, params.BaseToken)
 	if err != nil {
-		return nil, nil, nil, nil, err
+		return nil, nil, nil, err
 	}
 
 	reBalanceTxn, err := m.prepareReBalanceTxn(ctx, user, to, depositAmount, params.BaseToken)
 	if err != nil {
-		return nil, nil, nil, nil, err
+		return nil, nil, nil, err
 	}
 
 	transferFeeTxn, err := m.prepareTransferFeeTxn(baseFees, params.BaseToken)
 	if err != nil {
-		return nil, nil, nil, nil, err
+		return nil, nil, nil, err
 	}
 
 	return []safetypes.Transaction{
@@ -393,7 +465,7 @@ func (m *ReBalancingStrategy) prepareRedeemAndDepositTransactions(
 		approveTxn,
 		reBalanceTxn,
 		transferFeeTxn,
-	}, depositAmount, baseFees, yield, nil
+	}, depositAmount, baseFees, nil
 }
 
 func (m *ReBalancingStrategy) executeRedeemAndDeposit(
@@ -401,8 +473,9 @@ func (m *ReBalancingStrategy) executeRedeemAndDeposit(
 	logger log.Logger,
 	user, from, to common.Address,
 	depositAmount, baseFees, yield *big.Int,
+	fees *FeeBreakdown,
 	transactions []safetypes.Transaction,
-	prevState AutomationState,
+	prevState *AutomationState,
 	chainID int64,
 ) (*ExecutionLog, error) {
 	safeTx, err := encoders.GetEncodedSafeTx(
@@ -442,8 +515,9 @@ func (m *ReBalancingStrategy) executeRedeemAndDeposit(
 					InputAmount:    depositAmount.String(),
 					FeesAmount:     baseFees.String(),
 					GeneratedYield: yield.String(),
+					Fees:           fees,
 				},
-				Prev: &prevState,
+				Prev: prevState,
 			},
 		},
 	}, nil
@@ -589,27 +663,15 @@ func (m *ReBalancingStrategy) prepareRedeemTxn(
 }
 
 func (m *ReBalancingStrategy) calculateRedeemAndDepositAmounts(
-	ctx context.Context,
-	balance *big.Int,
-	metadata *ExecutionMetadata,
-	params *StrategyParams,
-	chainID int64,
-) (*big.Int, *big.Int, *big.Int, error) {
-	baseFeeAmt, err := m.calculateBaseFee(ctx, params.BaseToken, chainID)
-	if err != nil {
-		return nil, nil, nil, err
-	}
-	if err = m.validateBalance(balance, baseFeeAmt); err != nil {
-		return nil, nil, nil, err
-	}
-	yield, err := m.calculateYield(balance, metadata)
-	if err != nil {
-		return nil, nil, nil, err
+	balance, baseFeeAmt, yield *big.Int,
+) (*big.Int, *big.Int, error) {
+	if err := m.validateBalance(balance, baseFeeAmt); err != nil {
+		return nil, nil, err
 	}
 
 	baseFeeAmt = m.addYieldFees(baseFeeAmt, yield)
 	depositAmount := new(big.Int).Sub(balance, baseFeeAmt)
-	return baseFeeAmt, depositAmount, yield, nil
+	return baseFeeAmt, depositAmount, nil
 }
 
 func (m *ReBalancingStrategy) prepareReBalanceTxn(
@@ -647,41 +709,37 @@ func (m *ReBalancingStrategy) prepareReBalanceTxn(
 	}, nil
 }
 
-func (m *ReBalancingStrategy) calculateBaseFee(
-	ctx context.Context,
-	baseToken common.Address,
-	chainID int64,
-) (*big.Int, error) {
-	exactFeeAmt, ok := m.config.FeeConfig[baseToken.Hex()]
-	if ok {
-		return m.parseExactFee(exactFeeAmt)
+func (m *ReBalancingStrategy) validateBalance(balance, baseFeeAmt *big.Int) error {
+	if balance.Cmp(baseFeeAmt) <= 0 {
+		return fmt.Errorf("input does not cover base fees want=%s have=%s", baseFeeAmt.String(), balance.String())
 	}
-
-	// TODO: for mainnet calculate using current gasPrice and take gas units in config instead
-	return m.convertUSDFeeToToken(ctx, baseToken, chainID)
+	return nil
 }
 
-func (m *ReBalancingStrategy) parseExactFee(exactFeeAmt string) (*big.Int, error) {
-	baseFeeAmt, ok := new(big.Int).SetString(exactFeeAmt, 10)
-	if !ok {
-		return nil, fmt.Errorf("failed to parse base fee %s", exactFeeAmt)
+// yieldSince returns the yield balance earned since the previous execution and the state it recorded. A
+// subscription without a recorded state, one which never executed or whose log was lost, earned no yield
+// yet: its current balance becomes the baseline.
+func (m *ReBalancingStrategy) yieldSince(
+	logger log.Logger,
+	prevState *entity.ExecutionState,
+	balance *big.Int,
+) (*big.Int, *AutomationState, error) {
+	if prevState == nil {
+		logger.Warn("No previous execution state, taking the current balance as the yield baseline", "balance", balance.String())
+		return big.NewInt(0), nil, nil
 	}
-	return baseFeeAmt, nil
-}
 
-func (m *ReBalancingStrategy) convertUSDFeeToToken(
-	ctx context.Context,
-	baseToken common.Address,
-	chainID int64,
-) (*big.Int, error) {
-	return m.oracle.ConvertUSDToToken(ctx, chainID, m.config.BaseFeesInUSD, baseToken)
-}
+	metadata := &ExecutionMetadata{}
+	if err := json.Unmarshal(prevState.Metadata, metadata); err != nil {
+		return nil, nil, fmt.Errorf("failed to unmarshal metadata: %w", err)
+	}
 
-func (m *ReBalancingStrategy) validateBalance(balance, baseFeeAmt *big.Int) error {
-	if balance.Cmp(baseFeeAmt) <= 0 {
-		return fmt.Errorf("input does not cover base fees want=%s have=%s", baseFeeAmt.String(), balance.String())
+	yield, err := m.calculateYield(balance, metadata)
+	if err != nil {
+		return nil, nil, err
 	}
-	return nil
+
+	return yield, &metadata.TransitionState.Current, nil
 }
 
 func (m *ReBalancingStrategy) calculateYield(balance *big.Int, metadata *ExecutionMetadata) (*big.Int, error) {
@@ -0,0 +1,706 @@
+package morpho
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"strings"
+
+	"github.com/Brahma-fi/brahma-builder/internal/entity"
+	"github.com/Brahma-fi/go-safe/encoders"
+	safetypes "github.com/Brahma-fi/go-safe/types"
+	"github.com/ethereum/go-ethereum/common"
+	"go.temporal.io/sdk/log"
+)
+
+const _defaultMaxVaults = 3
+
+// vaultWeight is a vault balances are split across, with its share of them and the most it can take
+type vaultWeight struct {
+	vault  common.Address
+	weight float64
+	// limit is nil when the vault is uncapped
+	limit *big.Int
+}
+
+// allocationPlan is the transactions moving positions to their targets
+type allocationPlan struct {
+	transactions []safetypes.Transaction
+	// positions are the vault positions once the transactions are executed
+	positions map[common.Address]*big.Int
+	invested  *big.Int
+	// fees are the base and yield fees charged
+	fees  *big.Int
+	moves int
+}
+
+func (c AllocationConfig) validate() error {
+	switch c.Mode {
+	case "", AllocationSingle, AllocationSplit:
+	default:
+		return fmt.Errorf("unknown allocation mode %s", c.Mode)
+	}
+
+	switch c.Weighting {
+	case "", WeightingAPY, WeightingRisk:
+	default:
+		return fmt.Errorf("unknown allocation weighting %s", c.Weighting)
+	}
+
+	if _, err := c.minMove(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (c AllocationConfig) minMove() (*big.Int, error) {
+	if c.MinMove == "" {
+		return big.NewInt(0), nil
+	}
+
+	minMove, ok := new(big.Int).SetString(c.MinMove, 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid allocation min move %s", c.MinMove)
+	}
+
+	return minMove, nil
+}
+
+func (c AllocationConfig) riskScore(vault common.Address) float64 {
+	for address, score := range c.RiskScores {
+		if common.HexToAddress(address) == vault && score > 0 {
+			return score
+		}
+	}
+
+	return 1
+}
+
+// allocate splits the sub-account's balance across the best vaults, moving only the difference
+// between its positions and their targets
+func (m *ReBalancingStrategy) allocate(
+	ctx context.Context,
+	logger log.Logger,
+	execCtx entity.ExecCtx,
+	params *StrategyParams,
+	state *State,
+) (Result, error) {
+	chainID := int64(execCtx.Params.Subscription.ChainId)
+	positions, err := m.Positions(ctx, state.subaccount, state.vaults)
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to get positions: %w", err)
+	}
+
+	invested := sumAmounts(positions)
+	yield := big.NewInt(0)
+	var prevState *AutomationState
+	if invested.Sign() > 0 {
+		if yield, prevState, err = m.yieldSince(logger, execCtx.PrevState, invested); err != nil {
+			return Result{}, err
+		}
+	}
+
+	policy := m.config.Rebalance.merge(params.Rebalance)
+	holding, _, err := policy.durations()
+	if err != nil {
+		return Result{}, fmt.Errorf("invalid rebalance policy: %w", err)
+	}
+
+	// the rebalance policy holds positions where they are, the idle balance is deployed all the same
+	hold := ""
+	if invested.Sign() > 0 {
+		hold = holdingReason(execCtx, holding)
+	}
+
+	if invested.Sign() == 0 && !state.hasAvailableBalance {
+		return noAction("Nothing to execute", "no balance to deposit"), nil
+	}
+
+	weights := m.vaultWeights(state.vaults)
+	if len(weights) == 0 {
+		return noAction("Nothing to execute", "no vault to allocate to"), nil
+	}
+
+	planner := func(keepPositions bool) allocationPlanner {
+		return func(ctx context.Context, baseFees *big.Int) (*allocationPlan, error) {
+			return m.planAllocation(
+				ctx, state.subaccount, state.subAccBalance, positions, weights, baseFees, yield, params.BaseToken, keepPositions,
+			)
+		}
+	}
+
+	var (
+		p          *allocationPlan
+		baseFeeAmt *big.Int
+		fees       *FeeBreakdown
+	)
+	if hold == "" {
+		if p, baseFeeAmt, fees, err = m.chargeAllocation(ctx, state.subaccount, params.BaseToken, chainID, planner(false)); err != nil {
+			return Result{}, err
+		}
+	}
+
+	currentApy := weightedApy(positions, state.vaults)
+	if p != nil && invested.Sign() > 0 {
+		targetApy := weightedApy(p.positions, state.vaults)
+		if deltaBps := (targetApy - currentApy) * 1e4; deltaBps < policy.MinAPYDeltaBps {
+			hold = fmt.Sprintf("apy delta %.2fbps below %.2fbps", deltaBps, policy.MinAPYDeltaBps)
+		} else if err = policy.checkGain(p.invested, targetApy-currentApy, baseFeeAmt); errors.Is(err, errRebalanceNotWorth) {
+			hold = err.Error()
+		} else if err != nil {
+			return Result{}, err
+		}
+	}
+
+	if hold != "" {
+		p = nil
+		if state.hasAvailableBalance {
+			if p, baseFeeAmt, fees, err = m.chargeAllocation(ctx, state.subaccount, params.BaseToken, chainID, planner(true)); err != nil {
+				return Result{}, err
+			}
+		}
+	}
+
+	switch {
+	case p == nil && hold != "":
+		logger.Info("Holding positions", "reason", hold)
+		return noAction("Rebalance not worth it", hold), nil
+	case p == nil:
+		logger.Info("Allocation on target")
+		return noAction("No re-balance signal", "positions within min move of their targets"), nil
+	}
+
+	targetApy := weightedApy(p.positions, state.vaults)
+	reason := fmt.Sprintf("split across %d vaults at %.4f apy, from %.4f", len(p.positions), targetApy, currentApy)
+	if hold != "" {
+		logger.Info("Holding positions, deploying idle balance", "reason", hold)
+		reason = fmt.Sprintf("%s, positions held: %s", reason, hold)
+	}
+
+	fees.YieldFee = new(big.Int).Sub(p.fees, baseFeeAmt).String()
+	executionLog, err := m.executeAllocation(ctx, logger, state.subaccount, p, weights[0].vault, yield, fees, prevState, chainID)
+	if err != nil {
+		return Result{}, err
+	}
+
+	metadata, err := json.Marshal(executionLog.Metadata)
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to marshal execution metadata: %w", err)
+	}
+
+	return Result{
+		ExecutionResult: entity.ExecutionResult{
+			Action:  ActionAllocate,
+			TaskID:  executionLog.Metadata.TaskID,
+			Amounts: executionLog.Metadata.TransitionState.Current.Amounts(),
+			Reason:  reason,
+			Message: executionLog.Message,
+			State:   metadata,
+		},
+		TransitionState: &executionLog.Metadata.TransitionState,
+	}, nil
+}
+
+// allocationPlanner plans the allocation charging baseFees
+type allocationPlanner func(ctx context.Context, baseFees *big.Int) (*allocationPlan, error)
+
+// chargeAllocation plans the allocation with its base fees, nil when it moves no position by min move or more.
+// Positions already on target are not charged for.
+func (m *ReBalancingStrategy) chargeAllocation(
+	ctx context.Context,
+	subaccount, baseToken common.Address,
+	chainID int64,
+	plan allocationPlanner,
+) (*allocationPlan, *big.Int, *FeeBreakdown, error) {
+	if dryRun, err := plan(ctx, big.NewInt(0)); err != nil || dryRun.moves == 0 {
+		return nil, nil, nil, err
+	}
+
+	baseFeeAmt, fees, err := m.calculateBaseFee(ctx, subaccount, baseToken, chainID, ActionAllocate,
+		func(ctx context.Context, baseFees *big.Int) ([]safetypes.Transaction, error) {
+			p, err := plan(ctx, baseFees)
+			if err != nil {
+				return nil, err
+			}
+			return p.transactions, nil
+		},
+	)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	p, err := plan(ctx, baseFeeAmt)
+	if err != nil || p.moves == 0 {
+		return nil, nil, nil, err
+	}
+
+	return p, baseFeeAmt, fees, nil
+}
+
+// Positions returns the assets user holds in each of vaults it has shares of
+func (m *ReBalancingStrategy) Positions(
+	ctx context.Context,
+	user common.Address,
+	vaults []entity.VaultInfo,
+) (map[common.Address]*big.Int, error) {
+	positions := make(map[common.Address]*big.Int)
+	for _, vault := range vaults {
+		address := common.HexToAddress(vault.Address)
+		shares, err := m.client.Shares(ctx, address, user)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get shares: %w", err)
+		}
+
+		if shares.Sign() == 0 {
+			continue
+		}
+
+		balance, err := m.client.PreviewRedeem(ctx, address, user)
+		if err != nil {
+			return nil, fmt.Errorf("failed to preview redeem: %w", err)
+		}
+		positions[address] = balance
+	}
+
+	return positions, nil
+}
+
+// vaultWeights returns the best vaults to split balances across, by descending weight
+func (m *ReBalancingStrategy) vaultWeights(vaults []entity.VaultInfo) []vaultWeight {
+	cfg := m.config.Allocation
+	weights := make([]vaultWeight, 0, len(vaults))
+	for _, vault := range vaults {
+		address := common.HexToAddress(vault.Address)
+		// like findBestVault, vaults without liquidity are left out
+		if vault.State.NetApy <= 0 || vault.Liquidity.Underlying.Sign() <= 0 {
+			continue
+		}
+
+		if len(m.config.WhitelistedVaults) != 0 && !slices.Contains(m.config.WhitelistedVaults, address.Hex()) {
+			continue
+		}
+
+		weight := vaultWeight{vault: address, weight: vault.State.NetApy}
+		if cfg.Weighting == WeightingRisk {
+			weight.weight /= cfg.riskScore(address)
+		}
+
+		if cfg.MaxLiquidityShare > 0 {
+			weight.limit = minAmount(weight.limit, mulShare(&vault.Liquidity.Underlying.Int, cfg.MaxLiquidityShare))
+		}
+		if cfg.MaxTVLShare > 0 {
+			weight.limit = minAmount(weight.limit, mulShare(&vault.State.TotalAssets.Int, cfg.MaxTVLShare))
+		}
+
+		if weight.limit != nil && weight.limit.Sign() <= 0 {
+			continue
+		}
+
+		weights = append(weights, weight)
+	}
+
+	slices.SortStableFunc(weights, func(a, b vaultWeight) int {
+		switch {
+		case a.weight > b.weight:
+			return -1
+		case a.weight < b.weight:
+			return 1
+		default:
+			return 0
+		}
+	})
+
+	maxVaults := cfg.MaxVaults
+	if maxVaults == 0 {
+		maxVaults = _defaultMaxVaults
+	}
+
+	return weights[:min(maxVaults, len(weights))]
+}
+
+// fillTargets splits amount across vaults by weight. The share a vault cannot take over its limit
+// goes to the others, and what no vault can take is left out.
+func fillTargets(weights []vaultWeight, amount *big.Int) map[common.Address]*big.Int {
+	targets := make(map[common.Address]*big.Int, len(weights))
+	for _, w := range weights {
+		targets[w.vault] = big.NewInt(0)
+	}
+
+	remaining := new(big.Int).Set(amount)
+	// vaults without room take nothing, their share goes to the others from the first round
+	open := slices.DeleteFunc(slices.Clone(weights), func(w vaultWeight) bool {
+		return w.limit != nil && w.limit.Sign() <= 0
+	})
+	for len(open) != 0 && remaining.Sign() > 0 {
+		var total float64
+		for _, w := range open {
+			total += w.weight
+		}
+
+		shares := make([]*big.Int, len(open))
+		next := make([]vaultWeight, 0, len(open))
+		filled := new(big.Int)
+		for i, w := range open {
+			shares[i] = mulShare(remaining, w.weight/total)
+			if w.limit == nil {
+				next = append(next, w)
+				continue
+			}
+
+			room := new(big.Int).Sub(w.limit, targets[w.vault])
+			if shares[i].Cmp(room) < 0 {
+				next = append(next, w)
+				continue
+			}
+
+			// the vault is full, the others split what is left in the next round
+			targets[w.vault].Add(targets[w.vault], room)
+			filled.Add(filled, room)
+			shares[i] = nil
+		}
+
+		if filled.Sign() == 0 {
+			for i, w := range open {
+				if shares[i] != nil {
+					targets[w.vault].Add(targets[w.vault], shares[i])
+				}
+			}
+			break
+		}
+
+		remaining.Sub(remaining, filled)
+		open = next
+	}
+
+	return targets
+}
+
+// keptTargets are positions, plus amount split across weights within the room positions leave under their limits
+func keptTargets(weights []vaultWeight, positions map[common.Address]*big.Int, amount *big.Int) map[common.Address]*big.Int {
+	room := slices.Clone(weights)
+	for i, w := range room {
+		if w.limit != nil {
+			room[i].limit = new(big.Int).Sub(w.limit, amountOf(positions, w.vault))
+		}
+	}
+
+	targets := fillTargets(room, amount)
+	for vault, position := range positions {
+		targets[vault] = new(big.Int).Add(amountOf(targets, vault), position)
+	}
+
+	return targets
+}
+
+// planAllocation builds the transactions moving positions to their targets, once baseFees and the yield
+// fees are charged. Moves smaller than the configured min move are skipped. With keepPositions only the idle
+// balance is deployed, in the room positions leave, and the plan moves nothing when it doesn't cover fees.
+func (m *ReBalancingStrategy) planAllocation(
+	ctx context.Context,
+	user common.Address,
+	idle *big.Int,
+	positions map[common.Address]*big.Int,
+	weights []vaultWeight,
+	baseFees, yield *big.Int,
+	baseToken common.Address,
+	keepPositions bool,
+) (*allocationPlan, error) {
+	total := new(big.Int).Add(idle, sumAmounts(positions))
+	if err := m.validateBalance(total, baseFees); err != nil {
+		return nil, err
+	}
+
+	fees := m.addYieldFees(baseFees, yield)
+	if err := m.validateBalance(total, fees); err != nil {
+		return nil, err
+	}
+
+	minMove, err := m.config.Allocation.minMove()
+	if err != nil {
+		return nil, err
+	}
+
+	var targets map[common.Address]*big.Int
+	switch {
+	case !keepPositions:
+		targets = fillTargets(weights, new(big.Int).Sub(total, fees))
+	case idle.Cmp(fees) <= 0:
+		return &allocationPlan{positions: positions, invested: sumAmounts(positions), fees: fees}, nil
+	default:
+		targets = keptTargets(weights, positions, new(big.Int).Sub(idle, fees))
+	}
+
+	vaults := make([]common.Address, 0, len(positions)+len(targets))
+	for vault := range positions {
+		vaults = append(vaults, vault)
+	}
+	for vault := range targets {
+		if _, ok := positions[vault]; !ok {
+			vaults = append(vaults, vault)
+		}
+	}
+	slices.SortFunc(vaults, func(a, b common.Address) int { return bytes.Compare(a.Bytes(), b.Bytes()) })
+
+	plan := &allocationPlan{positions: make(map[common.Address]*big.Int), fees: fees}
+	cash := new(big.Int).Set(idle)
+	var deposits []vaultAmount
+	for _, vault := range vaults {
+		current, target := amountOf(positions, vault), amountOf(targets, vault)
+		plan.positions[vault] = current
+		diff := new(big.Int).Sub(target, current)
+		if diff.Sign() == 0 || new(big.Int).Abs(diff).Cmp(minMove) < 0 {
+			continue
+		}
+
+		if diff.Sign() > 0 {
+			deposits = append(deposits, vaultAmount{vault: vault, amount: diff})
+			continue
+		}
+
+		var txn *entity.Transaction
+		if target.Sign() == 0 {
+			txn, err = m.prepareRedeemTxn(ctx, vault, user)
+		} else {
+			txn, err = m.prepareWithdrawTxn(user, vault, new(big.Int).Neg(diff))
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		plan.transactions = append(plan.transactions, txn)
+		plan.positions[vault] = target
+		cash.Sub(cash, diff)
+		plan.moves++
+	}
+
+	if fees.Sign() > 0 {
+		if cash.Cmp(fees) < 0 {
+			return nil, fmt.Errorf("withdrawals do not cover fees want=%s have=%s", fees, cash)
+		}
+
+		transferFeeTxn, err := m.prepareTransferFeeTxn(fees, baseToken)
+		if err != nil {
+			return nil, err
+		}
+		plan.transactions = append(plan.transactions, transferFeeTxn)
+		cash.Sub(cash, fees)
+	}
+
+	// skipped withdrawals leave less cash than the targets assume, the largest deposits go first
+	slices.SortStableFunc(deposits, func(a, b vaultAmount) int { return b.amount.Cmp(a.amount) })
+	funded := deposits[:0]
+	for _, deposit := range deposits {
+		if cash.Sign() <= 0 {
+			break
+		}
+
+		deposit.amount = minAmount(deposit.amount, cash)
+		cash.Sub(cash, deposit.amount)
+		plan.positions[deposit.vault] = new(big.Int).Add(plan.positions[deposit.vault], deposit.amount)
+		funded = append(funded, deposit)
+	}
+
+	if len(funded) != 0 {
+		depositTxns, err := m.prepareDepositsTxns(ctx, user, funded, baseToken)
+		if err != nil {
+			return nil, err
+		}
+		plan.transactions = append(plan.transactions, depositTxns...)
+		plan.moves += len(funded)
+	}
+
+	for vault, amount := range plan.positions {
+		if amount.Sign() == 0 {
+			delete(plan.positions, vault)
+		}
+	}
+	plan.invested = sumAmounts(plan.positions)
+
+	return plan, nil
+}
+
+type vaultAmount struct {
+	vault  common.Address
+	amount *big.Int
+}
+
+func (m *ReBalancingStrategy) prepareWithdrawTxn(
+	user, vault common.Address,
+	assets *big.Int,
+) (*entity.Transaction, error) {
+	withdrawCallData, err := m.client.Withdraw(user, assets)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack withdraw call data: %w", err)
+	}
+
+	return &entity.Transaction{
+		Target: vault,
+		Val:    new(big.Int).SetInt64(0),
+		Data:   common.Bytes2Hex(withdrawCallData),
+	}, nil
+}
+
+// prepareDepositsTxns approves the bundler for the deposits, and bundles their transfer and deposits
+func (m *ReBalancingStrategy) prepareDepositsTxns(
+	ctx context.Context,
+	user common.Address,
+	deposits []vaultAmount,
+	baseToken common.Address,
+) ([]safetypes.Transaction, error) {
+	total := new(big.Int)
+	calls := make([]entity.BundlerCall, 0, len(deposits)+1)
+	for _, deposit := range deposits {
+		total.Add(total, deposit.amount)
+	}
+	calls = append(calls, entity.BundlerCall{
+		Type:   entity.BundlerCallTransferFrom,
+		Params: []any{baseToken, total},
+	})
+
+	for _, deposit := range deposits {
+		minShares, err := m.client.PreviewDeposit(ctx, deposit.vault, deposit.amount)
+		if err != nil {
+			return nil, fmt.Errorf("failed to preview deposit: %w", err)
+		}
+
+		out, _ := new(big.Float).Mul(new(big.Float).SetInt(minShares), new(big.Float).SetFloat64(slippage)).Int(nil)
+		calls = append(calls, entity.BundlerCall{
+			Type:   entity.BundlerCallDeposit,
+			Params: []any{deposit.vault, deposit.amount, out, user},
+		})
+	}
+
+	bundlerMultiCallData, err := m.client.Bundle(calls)
+	if err != nil {
+		return nil, fmt.Errorf("failed to bundle calls: %w", err)
+	}
+
+	approveTxn, err := m.prepareApproveTxn(total, baseToken)
+	if err != nil {
+		return nil, err
+	}
+
+	return []safetypes.Transaction{approveTxn, &entity.Transaction{
+		Target: m.bundlerAddress,
+		Val:    new(big.Int).SetInt64(0),
+		Data:   common.Bytes2Hex(bundlerMultiCallData),
+	}}, nil
+}
+
+func (m *ReBalancingStrategy) executeAllocation(
+	ctx context.Context,
+	logger log.Logger,
+	user common.Address,
+	plan *allocationPlan,
+	bestVault common.Address,
+	yield *big.Int,
+	fees *FeeBreakdown,
+	prevState *AutomationState,
+	chainID int64,
+) (*ExecutionLog, error) {
+	safeTx, err := encoders.GetEncodedSafeTx(
+		common.Address{},
+		common.HexToAddress(entity.SafeMultiSendCallOnly),
+		&entity.SafeMultiSendABI,
+		plan.transactions,
+		chainID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode safe transaction: %w", err)
+	}
+
+	req := &entity.SignAndExecuteRequest{
+		Subaccount: user.Hex(),
+		ChainID:    uint64(chainID),
+		Operation:  safeTx.Operation,
+		To:         safeTx.To.String(),
+		Value:      safeTx.Value.String(),
+		Data:       safeTx.Data.String(),
+	}
+
+	taskID, err := m.executor.Execute(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute transaction: %w", err)
+	}
+
+	allocations := make(map[string]string, len(plan.positions))
+	vaults := make([]string, 0, len(plan.positions))
+	for vault, amount := range plan.positions {
+		allocations[vault.Hex()] = amount.String()
+		vaults = append(vaults, vault.Hex())
+	}
+	slices.Sort(vaults)
+
+	logger.Info("Executed strategy signal", "taskID", taskID, "moves", plan.moves)
+	return &ExecutionLog{
+		Message: fmt.Sprintf("Allocated across %s", strings.Join(vaults, ", ")),
+		Metadata: ExecutionMetadata{
+			TaskID: taskID,
+			Req:    req,
+			TransitionState: TransitionState{
+				Current: AutomationState{
+					TargetVault:    bestVault,
+					InputAmount:    plan.invested.String(),
+					FeesAmount:     plan.fees.String(),
+					GeneratedYield: yield.String(),
+					Fees:           fees,
+					Allocations:    allocations,
+				},
+				Prev: prevState,
+			},
+		},
+	}, nil
+}
+
+// weightedApy is the net APY of positions as a whole
+func weightedApy(positions map[common.Address]*big.Int, vaults []entity.VaultInfo) float64 {
+	var earnings, total float64
+	for vault, amount := range positions {
+		f, _ := new(big.Float).SetInt(amount).Float64()
+		apy, _ := vaultApy(vaults, vault)
+		earnings += f * apy
+		total += f
+	}
+
+	if total == 0 {
+		return 0
+	}
+
+	return earnings / total
+}
+
+func sumAmounts(amounts map[common.Address]*big.Int) *big.Int {
+	sum := new(big.Int)
+	for _, amount := range amounts {
+		sum.Add(sum, amount)
+	}
+
+	return sum
+}
+
+func amountOf(amounts map[common.Address]*big.Int, vault common.Address) *big.Int {
+	if amount, ok := amounts[vault]; ok {
+		return amount
+	}
+
+	return big.NewInt(0)
+}
+
+// minAmount returns the smaller of a and b, a nil a being unbounded
+func minAmount(a, b *big.Int) *big.Int {
+	if a == nil || b.Cmp(a) < 0 {
+		return new(big.Int).Set(b)
+	}
+
+	return a
+}
+
+func mulShare(amount *big.Int, share float64) *big.Int {
+	out, _ := new(big.Float).Mul(new(big.Float).SetInt(amount), big.NewFloat(share)).Int(nil)
+	return out
+}
@@ -0,0 +1,305 @@
+package morpho
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/Brahma-fi/brahma-builder/internal/entity"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var (
+	_vaultA = common.HexToAddress("0xa")
+	_vaultB = common.HexToAddress("0xb")
+	_vaultC = common.HexToAddress("0xc")
+	_vaultD = common.HexToAddress("0xd")
+)
+
+// fakeMorphoClient builds the calls of a plan, every other call panics
+type fakeMorphoClient struct {
+	morphoClient
+	withdrawn []*big.Int
+	redeemed  []common.Address
+	deposited map[common.Address]*big.Int
+}
+
+func (f *fakeMorphoClient) Withdraw(_ common.Address, assets *big.Int) ([]byte, error) {
+	f.withdrawn = append(f.withdrawn, assets)
+	return []byte("withdraw"), nil
+}
+
+func (f *fakeMorphoClient) RedeemMax(_ context.Context, vault, _ common.Address) ([]byte, error) {
+	f.redeemed = append(f.redeemed, vault)
+	return []byte("redeem"), nil
+}
+
+func (f *fakeMorphoClient) PreviewDeposit(_ context.Context, _ common.Address, amt *big.Int) (*big.Int, error) {
+	return amt, nil
+}
+
+func (f *fakeMorphoClient) Bundle(calls []entity.BundlerCall) ([]byte, error) {
+	f.deposited = make(map[common.Address]*big.Int)
+	for _, call := range calls {
+		if call.Type == entity.BundlerCallDeposit {
+			f.deposited[call.Params[0].(common.Address)] = call.Params[1].(*big.Int)
+		}
+	}
+	return []byte("bundle"), nil
+}
+
+func amounts(amounts map[common.Address]int64) map[common.Address]*big.Int {
+	out := make(map[common.Address]*big.Int, len(amounts))
+	for vault, amount := range amounts {
+		out[vault] = big.NewInt(amount)
+	}
+	return out
+}
+
+func assertAmounts(t *testing.T, name string, got map[common.Address]*big.Int, want map[common.Address]int64) {
+	t.Helper()
+	for vault, amount := range want {
+		if amountOf(got, vault).Cmp(big.NewInt(amount)) != 0 {
+			t.Fatalf("%s of %s is %s, want %d", name, vault.Hex(), amountOf(got, vault), amount)
+		}
+	}
+
+	for vault, amount := range got {
+		if _, ok := want[vault]; !ok && amount.Sign() != 0 {
+			t.Fatalf("unexpected %s of %s: %s", name, vault.Hex(), amount)
+		}
+	}
+}
+
+func TestFillTargets(t *testing.T) {
+	tests := []struct {
+		name    string
+		weights []vaultWeight
+		amount  int64
+		want    map[common.Address]int64
+	}{
+		{
+			name:    "split by weight",
+			weights: []vaultWeight{{vault: _vaultA, weight: 3}, {vault: _vaultB, weight: 1}},
+			amount:  1000,
+			want:    map[common.Address]int64{_vaultA: 750, _vaultB: 250},
+		},
+		{
+			name:    "capped vault's share goes to the others",
+			weights: []vaultWeight{{vault: _vaultA, weight: 1, limit: big.NewInt(100)}, {vault: _vaultB, weight: 1}},
+			amount:  1000,
+			want:    map[common.Address]int64{_vaultA: 100, _vaultB: 900},
+		},
+		{
+			name:    "vault without room",
+			weights: []vaultWeight{{vault: _vaultA, weight: 1, limit: big.NewInt(0)}, {vault: _vaultB, weight: 1, limit: big.NewInt(1e6)}},
+			amount:  1000,
+			want:    map[common.Address]int64{_vaultB: 1000},
+		},
+		{
+			name: "what no vault takes is left out",
+			weights: []vaultWeight{
+				{vault: _vaultA, weight: 1, limit: big.NewInt(100)},
+				{vault: _vaultB, weight: 1, limit: big.NewInt(200)},
+			},
+			amount: 1000,
+			want:   map[common.Address]int64{_vaultA: 100, _vaultB: 200},
+		},
+		{
+			name: "caps filled over several rounds",
+			weights: []vaultWeight{
+				{vault: _vaultA, weight: 2, limit: big.NewInt(100)},
+				{vault: _vaultB, weight: 1, limit: big.NewInt(300)},
+				{vault: _vaultC, weight: 1},
+			},
+			amount: 1000,
+			want:   map[common.Address]int64{_vaultA: 100, _vaultB: 300, _vaultC: 600},
+		},
+		{
+			name:    "nothing to fill",
+			weights: []vaultWeight{{vault: _vaultA, weight: 1}},
+			amount:  0,
+			want:    map[common.Address]int64{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertAmounts(t, "target", fillTargets(tt.weights, big.NewInt(tt.amount)), tt.want)
+		})
+	}
+}
+
+func TestVaultWeightsSkipsVaultsWithoutRoom(t *testing.T) {
+	vault := func(address common.Address, apy float64, liquidity int64) entity.VaultInfo {
+		v := entity.VaultInfo{Address: address.Hex()}
+		v.State.NetApy = apy
+		v.State.TotalAssets.SetInt64(1e9)
+		v.Liquidity.Underlying.SetInt64(liquidity)
+		return v
+	}
+
+	m := &ReBalancingStrategy{config: &Config{Allocation: AllocationConfig{MaxLiquidityShare: 0.5}}}
+	weights := m.vaultWeights([]entity.VaultInfo{
+		vault(_vaultA, 0.05, 0),
+		// half of its liquidity rounds down to nothing
+		vault(_vaultB, 0.04, 1),
+		vault(_vaultC, 0.03, 1000),
+		vault(_vaultD, 0, 1000),
+	})
+
+	if len(weights) != 1 || weights[0].vault != _vaultC || weights[0].limit.Cmp(big.NewInt(500)) != 0 {
+		t.Fatalf("unexpected weights %+v", weights)
+	}
+}
+
+func TestPlanAllocation(t *testing.T) {
+	tests := []struct {
+		name      string
+		minMove   string
+		idle      int64
+		positions map[common.Address]int64
+		weights   []vaultWeight
+		// keep holds positions where they are, only the idle balance is deployed
+		keep bool
+		// base fees are 10, without yield
+		wantErr       bool
+		wantPositions map[common.Address]int64
+		wantDeposits  map[common.Address]int64
+		wantWithdrawn int
+		wantRedeemed  []common.Address
+		wantMoves     int
+	}{
+		{
+			name:          "idle balance split by weight",
+			idle:          1010,
+			weights:       []vaultWeight{{vault: _vaultA, weight: 3}, {vault: _vaultB, weight: 1}},
+			wantPositions: map[common.Address]int64{_vaultA: 750, _vaultB: 250},
+			wantDeposits:  map[common.Address]int64{_vaultA: 750, _vaultB: 250},
+			wantMoves:     2,
+		},
+		{
+			name:          "capped vault",
+			idle:          1010,
+			weights:       []vaultWeight{{vault: _vaultA, weight: 1, limit: big.NewInt(100)}, {vault: _vaultB, weight: 1}},
+			wantPositions: map[common.Address]int64{_vaultA: 100, _vaultB: 900},
+			wantDeposits:  map[common.Address]int64{_vaultA: 100, _vaultB: 900},
+			wantMoves:     2,
+		},
+		{
+			name:          "vault without room",
+			idle:          1010,
+			weights:       []vaultWeight{{vault: _vaultA, weight: 1, limit: big.NewInt(0)}, {vault: _vaultB, weight: 1, limit: big.NewInt(1e6)}},
+			wantPositions: map[common.Address]int64{_vaultB: 1000},
+			wantDeposits:  map[common.Address]int64{_vaultB: 1000},
+			wantMoves:     1,
+		},
+		{
+			name:          "moves below min move",
+			minMove:       "50",
+			idle:          20,
+			positions:     map[common.Address]int64{_vaultA: 500, _vaultB: 490},
+			weights:       []vaultWeight{{vault: _vaultA, weight: 1}, {vault: _vaultB, weight: 1}},
+			wantPositions: map[common.Address]int64{_vaultA: 500, _vaultB: 490},
+			wantMoves:     0,
+		},
+		{
+			// b's withdrawal is below min move, c gets the cash a's withdrawal frees
+			name:          "skipped withdrawal",
+			minMove:       "200",
+			idle:          10,
+			positions:     map[common.Address]int64{_vaultA: 600, _vaultB: 400},
+			weights:       []vaultWeight{{vault: _vaultA, weight: 1}, {vault: _vaultB, weight: 1}, {vault: _vaultC, weight: 2}},
+			wantPositions: map[common.Address]int64{_vaultA: 250, _vaultB: 400, _vaultC: 350},
+			wantDeposits:  map[common.Address]int64{_vaultC: 350},
+			wantWithdrawn: 1,
+			wantMoves:     2,
+		},
+		{
+			name:          "vault left entirely",
+			idle:          10,
+			positions:     map[common.Address]int64{_vaultD: 500},
+			weights:       []vaultWeight{{vault: _vaultB, weight: 1}},
+			wantPositions: map[common.Address]int64{_vaultB: 500},
+			wantDeposits:  map[common.Address]int64{_vaultB: 500},
+			wantRedeemed:  []common.Address{_vaultD},
+			wantMoves:     2,
+		},
+		{
+			name:          "positions kept, idle balance deployed within their limits",
+			idle:          310,
+			positions:     map[common.Address]int64{_vaultA: 500, _vaultD: 400},
+			weights:       []vaultWeight{{vault: _vaultA, weight: 1, limit: big.NewInt(600)}, {vault: _vaultB, weight: 1}},
+			keep:          true,
+			wantPositions: map[common.Address]int64{_vaultA: 600, _vaultB: 200, _vaultD: 400},
+			wantDeposits:  map[common.Address]int64{_vaultA: 100, _vaultB: 200},
+			wantMoves:     2,
+		},
+		{
+			name:          "positions kept, idle balance below fees",
+			idle:          10,
+			positions:     map[common.Address]int64{_vaultD: 500},
+			weights:       []vaultWeight{{vault: _vaultB, weight: 1}},
+			keep:          true,
+			wantPositions: map[common.Address]int64{_vaultD: 500},
+			wantMoves:     0,
+		},
+		{
+			name:    "balance below fees",
+			idle:    5,
+			weights: []vaultWeight{{vault: _vaultA, weight: 1}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &fakeMorphoClient{}
+			m := &ReBalancingStrategy{
+				client:         client,
+				config:         &Config{FeeReceiver: "0xfee", Allocation: AllocationConfig{MinMove: tt.minMove}},
+				bundlerAddress: common.HexToAddress("0xb0"),
+			}
+
+			plan, err := m.planAllocation(
+				context.Background(),
+				common.HexToAddress("0x5a"),
+				big.NewInt(tt.idle),
+				amounts(tt.positions),
+				tt.weights,
+				big.NewInt(10),
+				big.NewInt(0),
+				common.HexToAddress("0x70c0"),
+				tt.keep,
+			)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if plan.moves != tt.wantMoves {
+				t.Fatalf("got %d moves, want %d", plan.moves, tt.wantMoves)
+			}
+
+			assertAmounts(t, "position", plan.positions, tt.wantPositions)
+			assertAmounts(t, "deposit", client.deposited, tt.wantDeposits)
+			if len(client.withdrawn) != tt.wantWithdrawn {
+				t.Fatalf("got %d withdrawals, want %d", len(client.withdrawn), tt.wantWithdrawn)
+			}
+
+			if len(client.redeemed) != len(tt.wantRedeemed) || (len(tt.wantRedeemed) != 0 && client.redeemed[0] != tt.wantRedeemed[0]) {
+				t.Fatalf("redeemed %v, want %v", client.redeemed, tt.wantRedeemed)
+			}
+
+			if plan.fees.Cmp(big.NewInt(10)) != 0 {
+				t.Fatalf("got fees %s, want 10", plan.fees)
+			}
+		})
+	}
+}
@@ -1,12 +1,29 @@
 package morpho
 
 import (
+	"fmt"
+
+	"github.com/Brahma-fi/brahma-builder/internal/entity"
+	"github.com/Brahma-fi/brahma-builder/internal/usecase/strategies"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/mitchellh/mapstructure"
 )
 
 const (
 	slippage = 0.9995
+
+	_rebalancerActivityName = "MorphoRebalancer"
+)
+
+var (
+	RebalancerMainnet = strategies.Definition[StrategyParams, Result]{
+		ID:           entity.StrategyIDMorphoRebalancerMainnet,
+		ActivityName: _rebalancerActivityName,
+	}
+	RebalancerBase = strategies.Definition[StrategyParams, Result]{
+		ID:           entity.StrategyIDMorphoRebalancerBase,
+		ActivityName: _rebalancerActivityName,
+	}
 )
 
 type Config struct {
@@ -17,6 +34,70 @@ type Config struct {
 	BundlerAddress    string            `json:"bundlerAddress"`
 	FeeConfig         map[string]string `json:"feeConfig"`
 	WhitelistedVaults []string          `json:"whitelistedVaults"`
+	// GasFee charges the gas cost of executions instead of BaseFeesInUSD when set
+	GasFee *GasFeeConfig `json:"gasFee"`
+	// Rebalance is the default rebalance policy of subscriptions
+	Rebalance RebalancePolicy `json:"rebalance"`
+	// Allocation is how balances are spread across vaults
+	Allocation AllocationConfig `json:"allocation"`
+}
+
+type AllocationMode string
+
+const (
+	// AllocationSingle puts the whole balance in the best vault
+	AllocationSingle AllocationMode = "single"
+	// AllocationSplit spreads the balance across the best vaults. The rebalance policy only holds positions,
+	// the idle balance is deployed regardless.
+	AllocationSplit AllocationMode = "split"
+)
+
+type AllocationWeighting string
+
+const (
+	// WeightingAPY weights vaults by their net APY
+	WeightingAPY AllocationWeighting = "apy"
+	// WeightingRisk weights vaults by their net APY over their risk score
+	WeightingRisk AllocationWeighting = "risk"
+)
+
+type AllocationConfig struct {
+	// Mode is AllocationSingle when empty
+	Mode AllocationMode `json:"mode"`
+	// MaxVaults is how many vaults a balance is split across, 3 when zero
+	MaxVaults int `json:"maxVaults"`
+	// MaxLiquidityShare caps the allocation to a vault at this share of its available liquidity, uncapped when zero
+	MaxLiquidityShare float64 `json:"maxLiquidityShare"`
+	// MaxTVLShare caps the allocation to a vault at this share of its total assets, uncapped when zero
+	MaxTVLShare float64 `json:"maxTVLShare"`
+	// Weighting is WeightingAPY when empty
+	Weighting AllocationWeighting `json:"weighting"`
+	// RiskScores are keyed by vault address, vaults without one score 1
+	RiskScores map[string]float64 `json:"riskScores"`
+	// MinMove is the smallest position change made, in base token units, smaller ones are left as they are
+	MinMove string `json:"minMove"`
+}
+
+type GasFeeConfig struct {
+	// NativeToken is the wrapped native token gas is priced with, WETH on mainnet
+	NativeToken string `json:"nativeToken"`
+	// GasUnits are charged per action, keyed by entity.ExecutionAction, when the execution's gas cannot be estimated
+	GasUnits map[string]uint64 `json:"gasUnits"`
+	// OverheadGas is added to estimates for the executor plugin's validation and the intrinsic gas of the transaction
+	OverheadGas uint64 `json:"overheadGas"`
+	// Markup is the share of the gas cost charged on top of it, 0.2 charges 120% of the cost
+	Markup float64 `json:"markup"`
+}
+
+// Rebalancer returns the rebalancer strategy of the executor with ExecutorConfig.ID id
+func Rebalancer(id string) (strategies.Definition[StrategyParams, Result], error) {
+	for _, rebalancer := range []strategies.Definition[StrategyParams, Result]{RebalancerMainnet, RebalancerBase} {
+		if rebalancer.ID == id {
+			return rebalancer, nil
+		}
+	}
+
+	return strategies.Definition[StrategyParams, Result]{}, fmt.Errorf("unknown morpho rebalancer %s", id)
 }
 
 func ParseConfig(raw map[string]any) (*Config, error) {
@@ -30,4 +111,6 @@ func ParseConfig(raw map[string]any) (*Config, error) {
 
 type StrategyParams struct {
 	BaseToken common.Address `json:"baseToken"`
+	// Rebalance overrides the fields it sets of the configured rebalance policy
+	Rebalance *RebalancePolicy `json:"rebalance,omitempty"`
 }
@@ -0,0 +1,199 @@
+package morpho
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/Brahma-fi/brahma-builder/internal/entity"
+	"github.com/Brahma-fi/go-safe/contracts/safe"
+	"github.com/Brahma-fi/go-safe/encoders"
+	"github.com/Brahma-fi/go-safe/gasestimate"
+	safetypes "github.com/Brahma-fi/go-safe/types"
+	"github.com/ethereum/go-ethereum/common"
+	"go.temporal.io/sdk/activity"
+)
+
+// buildTransactions builds the transactions of an execution charging baseFee
+type buildTransactions func(ctx context.Context, baseFee *big.Int) ([]safetypes.Transaction, error)
+
+// calculateBaseFee returns the fee charged for an execution of action, build is used to estimate its gas
+func (m *ReBalancingStrategy) calculateBaseFee(
+	ctx context.Context,
+	subaccount, baseToken common.Address,
+	chainID int64,
+	action entity.ExecutionAction,
+	build buildTransactions,
+) (*big.Int, *FeeBreakdown, error) {
+	exactFeeAmt, ok := m.config.FeeConfig[baseToken.Hex()]
+	if ok {
+		baseFeeAmt, err := m.parseExactFee(exactFeeAmt)
+		if err != nil {
+			return nil, nil, err
+		}
+		return baseFeeAmt, &FeeBreakdown{Method: FeeMethodExact, BaseFee: baseFeeAmt.String()}, nil
+	}
+
+	if m.config.GasFee == nil {
+		baseFeeAmt, err := m.convertUSDFeeToToken(ctx, baseToken, chainID)
+		if err != nil {
+			return nil, nil, err
+		}
+		return baseFeeAmt, &FeeBreakdown{Method: FeeMethodUSD, BaseFee: baseFeeAmt.String()}, nil
+	}
+
+	baseFeeAmt, gasCost, err := m.gasFee(ctx, subaccount, baseToken, chainID, action, build)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to calculate gas fee: %w", err)
+	}
+
+	return baseFeeAmt, &FeeBreakdown{Method: FeeMethodGas, BaseFee: baseFeeAmt.String(), Gas: gasCost}, nil
+}
+
+func (m *ReBalancingStrategy) parseExactFee(exactFeeAmt string) (*big.Int, error) {
+	baseFeeAmt, ok := new(big.Int).SetString(exactFeeAmt, 10)
+	if !ok {
+		return nil, fmt.Errorf("failed to parse base fee %s", exactFeeAmt)
+	}
+	return baseFeeAmt, nil
+}
+
+func (m *ReBalancingStrategy) convertUSDFeeToToken(
+	ctx context.Context,
+	baseToken common.Address,
+	chainID int64,
+) (*big.Int, error) {
+	return m.oracle.ConvertUSDToToken(ctx, chainID, m.config.BaseFeesInUSD, baseToken)
+}
+
+// gasFee prices the gas of the execution in the native token, and converts it with the markup to baseToken
+func (m *ReBalancingStrategy) gasFee(
+	ctx context.Context,
+	subaccount, baseToken common.Address,
+	chainID int64,
+	action entity.ExecutionAction,
+	build buildTransactions,
+) (*big.Int, *GasCost, error) {
+	cfg := m.config.GasFee
+	units, estimated, err := m.gasUnits(ctx, subaccount, chainID, action, build)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	price, err := m.gasPrice(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cost := new(big.Int).Mul(new(big.Int).SetUint64(units), price)
+	costUSD, err := m.oracle.ConvertTokenToUSD(ctx, chainID, cost, common.HexToAddress(cfg.NativeToken))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to price gas: %w", err)
+	}
+
+	baseFeeAmt, err := m.oracle.ConvertUSDToToken(ctx, chainID, costUSD*(1+cfg.Markup), baseToken)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return baseFeeAmt, &GasCost{
+		Units:     units,
+		Estimated: estimated,
+		Price:     price.String(),
+		Cost:      cost.String(),
+		CostUSD:   costUSD,
+		Markup:    cfg.Markup,
+	}, nil
+}
+
+// gasUnits estimates the gas of the execution, falling back to the units configured for action.
+// The fee transfer is estimated with the smallest amount, its gas does not depend on it.
+func (m *ReBalancingStrategy) gasUnits(
+	ctx context.Context,
+	subaccount common.Address,
+	chainID int64,
+	action entity.ExecutionAction,
+	build buildTransactions,
+) (uint64, bool, error) {
+	cfg := m.config.GasFee
+	transactions, err := build(ctx, big.NewInt(1))
+	if err == nil {
+		var units uint64
+		if units, err = m.estimateGas(ctx, subaccount, transactions, chainID); err == nil {
+			return units + cfg.OverheadGas, true, nil
+		}
+	}
+
+	units, ok := cfg.GasUnits[string(action)]
+	if !ok {
+		return 0, false, fmt.Errorf("failed to estimate gas and no gas units configured for %s: %w", action, err)
+	}
+
+	activity.GetLogger(ctx).Warn("Falling back to configured gas units", "action", action, "units", units, "error", err)
+	return units, false, nil
+}
+
+// estimateGas estimates transactions as the executor plugin executes them, as a module of subaccount
+func (m *ReBalancingStrategy) estimateGas(
+	ctx context.Context,
+	subaccount common.Address,
+	transactions []safetypes.Transaction,
+	chainID int64,
+) (uint64, error) {
+	safeTx, err := encoders.GetEncodedSafeTx(
+		common.Address{},
+		common.HexToAddress(entity.SafeMultiSendCallOnly),
+		&entity.SafeMultiSendABI,
+		transactions,
+		chainID,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode safe transaction: %w", err)
+	}
+
+	safeABI, err := safe.SafeMetaData.GetAbi()
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse safe ABI: %w", err)
+	}
+
+	callData, err := safeABI.Pack(
+		"execTransactionFromModule",
+		safeTx.To.Address(),
+		(*big.Int)(&safeTx.Value),
+		[]byte(*safeTx.Data),
+		safeTx.Operation,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to pack module transaction: %w", err)
+	}
+
+	return gasestimate.EstimateSafeTransactionGasLimit(ctx, m.gas, gasestimate.EstimateSafeTransactionGasRequest{
+		From:     m.pluginAddress,
+		To:       subaccount,
+		CallData: callData,
+		Value:    big.NewInt(0),
+	})
+}
+
+// gasPrice is the base fee of the latest block plus the suggested tip, or the suggested gas price before EIP-1559
+func (m *ReBalancingStrategy) gasPrice(ctx context.Context) (*big.Int, error) {
+	header, err := m.gas.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latest header: %w", err)
+	}
+
+	if header.BaseFee == nil {
+		price, err := m.gas.SuggestGasPrice(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to suggest gas price: %w", err)
+		}
+		return price, nil
+	}
+
+	tip, err := m.gas.SuggestGasTipCap(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to suggest gas tip: %w", err)
+	}
+
+	return new(big.Int).Add(header.BaseFee, tip), nil
+}
@@ -5,8 +5,9 @@ import (
 	"math/big"
 
 	"github.com/Brahma-fi/brahma-builder/internal/entity"
+	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/google/uuid"
+	"github.com/ethereum/go-ethereum/core/types"
 )
 
 type morphoClient interface {
@@ -20,6 +21,10 @@ type morphoClient interface {
 		depositor common.Address,
 		amt *big.Int,
 	) ([]byte, error)
+	Withdraw(
+		depositor common.Address,
+		assets *big.Int,
+	) ([]byte, error)
 	RedeemMax(
 		ctx context.Context,
 		vaultAddr common.Address,
@@ -49,13 +54,6 @@ type consoleExecutor interface {
 	Execute(ctx context.Context, req *entity.SignAndExecuteRequest) (string, error)
 }
 
-type executionsLogRepo interface {
-	LatestBySubID(
-		ctx context.Context,
-		subID uuid.UUID,
-	) (*entity.Log, error)
-}
-
 type pricingOracle interface {
 	ConvertUSDToToken(
 		ctx context.Context,
@@ -63,4 +61,18 @@ type pricingOracle interface {
 		amtUSD float64,
 		tokenAddress common.Address,
 	) (*big.Int, error)
+	ConvertTokenToUSD(
+		ctx context.Context,
+		chainID int64,
+		amt *big.Int,
+		tokenAddress common.Address,
+	) (float64, error)
+}
+
+type gasOracle interface {
+	EstimateGas(ctx context.Context, msg ethereum.CallMsg) (uint64, error)
+	CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error)
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+	SuggestGasPrice(ctx context.Context) (*big.Int, error)
+	SuggestGasTipCap(ctx context.Context) (*big.Int, error)
 }
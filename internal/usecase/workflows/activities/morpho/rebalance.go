@@ -0,0 +1,134 @@
+package morpho
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/Brahma-fi/brahma-builder/internal/entity"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const _year = 365 * 24 * time.Hour
+
+// errRebalanceNotWorth is returned when moving to a better vault costs more than it is expected to earn
+var errRebalanceNotWorth = errors.New("rebalance not worth it")
+
+// RebalancePolicy keeps funds in their vault unless a move is worth it, zero values disable each check
+type RebalancePolicy struct {
+	// MinAPYDeltaBps is how many basis points of net APY the best vault must beat the current one by
+	MinAPYDeltaBps float64 `json:"minApyDeltaBps"`
+	// MinHoldingPeriod is how long funds stay in a vault before being moved, e.g. 72h
+	MinHoldingPeriod string `json:"minHoldingPeriod"`
+	// GainHorizon is the period the APY gain of a move is expected over, and compared with its fees, e.g. 720h
+	GainHorizon string `json:"gainHorizon"`
+}
+
+// merge returns the policy with the fields set in override replacing its own
+func (p RebalancePolicy) merge(override *RebalancePolicy) RebalancePolicy {
+	if override == nil {
+		return p
+	}
+
+	if override.MinAPYDeltaBps != 0 {
+		p.MinAPYDeltaBps = override.MinAPYDeltaBps
+	}
+	if override.MinHoldingPeriod != "" {
+		p.MinHoldingPeriod = override.MinHoldingPeriod
+	}
+	if override.GainHorizon != "" {
+		p.GainHorizon = override.GainHorizon
+	}
+
+	return p
+}
+
+func (p RebalancePolicy) durations() (holding, horizon time.Duration, err error) {
+	if p.MinHoldingPeriod != "" {
+		if holding, err = time.ParseDuration(p.MinHoldingPeriod); err != nil {
+			return 0, 0, fmt.Errorf("invalid min holding period: %w", err)
+		}
+	}
+
+	if p.GainHorizon != "" {
+		if horizon, err = time.ParseDuration(p.GainHorizon); err != nil {
+			return 0, 0, fmt.Errorf("invalid gain horizon: %w", err)
+		}
+	}
+
+	return holding, horizon, nil
+}
+
+// holdReason returns why funds should stay in currentVault rather than move to a vault at bestApy,
+// or an empty string when the APY delta and holding period allow the move
+func (p RebalancePolicy) holdReason(
+	execCtx entity.ExecCtx,
+	vaults []entity.VaultInfo,
+	currentVault common.Address,
+	bestApy float64,
+) (string, error) {
+	holding, _, err := p.durations()
+	if err != nil {
+		return "", err
+	}
+
+	if currentApy, ok := vaultApy(vaults, currentVault); ok {
+		if deltaBps := (bestApy - currentApy) * 1e4; deltaBps < p.MinAPYDeltaBps {
+			return fmt.Sprintf("apy delta %.2fbps below %.2fbps", deltaBps, p.MinAPYDeltaBps), nil
+		}
+	}
+
+	return holdingReason(execCtx, holding), nil
+}
+
+// holdingReason returns why funds should stay where they are when they were moved less than holding ago.
+// Funds without a recorded state aren't held: the schedule's previous run doesn't tell when they last moved.
+func holdingReason(execCtx entity.ExecCtx, holding time.Duration) string {
+	if holding == 0 || execCtx.PrevState == nil {
+		return ""
+	}
+
+	// the last state was recorded by the execution that last moved funds
+	movedAt := execCtx.PrevState.CreatedAt
+
+	now := execCtx.TriggeredAt
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	if held := now.Sub(movedAt); !movedAt.IsZero() && held < holding {
+		return fmt.Sprintf("held %s of %s", held.Round(time.Second), holding)
+	}
+
+	return ""
+}
+
+// checkGain fails with errRebalanceNotWorth when moving balance for apyDelta over the gain horizon
+// earns less than fees
+func (p RebalancePolicy) checkGain(balance *big.Int, apyDelta float64, fees *big.Int) error {
+	_, horizon, err := p.durations()
+	if err != nil || horizon == 0 {
+		return err
+	}
+
+	gain, _ := new(big.Float).Mul(
+		new(big.Float).SetInt(balance),
+		big.NewFloat(apyDelta*horizon.Hours()/_year.Hours()),
+	).Int(nil)
+	if gain.Cmp(fees) <= 0 {
+		return fmt.Errorf("%w: expected gain %s over %s does not cover fees %s", errRebalanceNotWorth, gain, horizon, fees)
+	}
+
+	return nil
+}
+
+func vaultApy(vaults []entity.VaultInfo, vault common.Address) (float64, bool) {
+	for _, v := range vaults {
+		if common.HexToAddress(v.Address) == vault {
+			return v.State.NetApy, true
+		}
+	}
+
+	return 0, false
+}
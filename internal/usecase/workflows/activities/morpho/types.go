@@ -14,6 +14,40 @@ type AutomationState struct {
 	FeesAmount string `json:"feesAmount"`
 	// amount which was generated as yield
 	GeneratedYield string `json:"generatedYield"`
+	// how FeesAmount was computed, nil on executions recorded before it was tracked
+	Fees *FeeBreakdown `json:"fees,omitempty"`
+	// amount in each vault keyed by address when the balance is split, TargetVault is then the best of them
+	Allocations map[string]string `json:"allocations,omitempty"`
+}
+
+type FeeMethod string
+
+const (
+	FeeMethodExact FeeMethod = "exact"
+	FeeMethodUSD   FeeMethod = "usd"
+	FeeMethodGas   FeeMethod = "gas"
+)
+
+// FeeBreakdown splits the fees of an execution, amounts are in base token units
+type FeeBreakdown struct {
+	Method FeeMethod `json:"method"`
+	// BaseFee is the fee charged for the execution itself
+	BaseFee string `json:"baseFee"`
+	// YieldFee is the share of the generated yield charged
+	YieldFee string `json:"yieldFee"`
+	// Gas is what BaseFee was priced from with FeeMethodGas
+	Gas *GasCost `json:"gas,omitempty"`
+}
+
+type GasCost struct {
+	Units uint64 `json:"units"`
+	// Estimated is false when Units were taken from configuration
+	Estimated bool `json:"estimated"`
+	// Price and Cost are in wei
+	Price   string  `json:"price"`
+	Cost    string  `json:"cost"`
+	CostUSD float64 `json:"costUSD"`
+	Markup  float64 `json:"markup"`
 }
 
 type TransitionState struct {
@@ -31,3 +65,25 @@ type ExecutionLog struct {
 	Message  string
 	Metadata ExecutionMetadata
 }
+
+const (
+	ActionDeposit   entity.ExecutionAction = "deposit"
+	ActionRebalance entity.ExecutionAction = "rebalance"
+	ActionAllocate  entity.ExecutionAction = "allocate"
+)
+
+// Amounts returns the amounts of the transition, keyed as in entity.ExecutionResult's Amounts
+func (s AutomationState) Amounts() map[string]string {
+	return map[string]string{
+		"input": s.InputAmount,
+		"fees":  s.FeesAmount,
+		"yield": s.GeneratedYield,
+	}
+}
+
+// Result is the outcome of a rebalancer execution
+type Result struct {
+	entity.ExecutionResult
+	// TransitionState is the position change the execution submitted, nil when nothing was submitted
+	TransitionState *TransitionState `json:"transitionState,omitempty"`
+}
@@ -0,0 +1,127 @@
+package activities
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Brahma-fi/brahma-builder/internal/entity"
+	"github.com/google/uuid"
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+)
+
+const _errTypeInvalidSubscriptionID = "InvalidSubscriptionID"
+
+// StateActivity reads and records the per subscription state strategies carry from one execution to the next
+type StateActivity struct {
+	logs executionsLog
+}
+
+func NewStateActivity(logs executionsLog) *StateActivity {
+	return &StateActivity{logs: logs}
+}
+
+// LatestState returns the state recorded by the last completed execution of subID, nil when there is none
+func (s *StateActivity) LatestState(ctx context.Context, subID string) (*entity.ExecutionState, error) {
+	id, err := parseSubscriptionID(subID)
+	if err != nil {
+		return nil, err
+	}
+
+	latest, err := s.logs.LatestBySubID(ctx, id)
+	switch {
+	case errors.Is(err, entity.ErrExecutionLogNotFound):
+		return nil, nil
+	case err != nil:
+		return nil, err
+	}
+
+	metadata, err := rawMetadata(latest.Metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	return &entity.ExecutionState{
+		Metadata:  metadata,
+		TxHash:    latest.OutputTxnHash,
+		CreatedAt: latest.CreatedAt,
+	}, nil
+}
+
+// SaveExecution records the execution of a run, keyed by the run so a retry stores it once. Only a result
+// carrying state gives the log row metadata, the rows LatestState reads from.
+func (s *StateActivity) SaveExecution(
+	ctx context.Context,
+	config entity.ExecuteWorkflowParams,
+	result entity.ExecutionResult,
+) error {
+	subID, err := parseSubscriptionID(config.Params.Subscription.Id)
+	if err != nil {
+		return err
+	}
+
+	runID, err := uuid.Parse(activity.GetInfo(ctx).WorkflowExecution.RunID)
+	if err != nil {
+		return temporal.NewNonRetryableApplicationError("invalid run id", "InvalidRunID", err)
+	}
+
+	var metadata entity.Jsonb
+	if len(result.State) != 0 {
+		metadata = []byte(result.State)
+	}
+
+	return s.logs.Insert(ctx, &entity.Log{
+		ID:                runID,
+		SubscriptionID:    subID,
+		ChainID:           config.Params.ChainID,
+		Metadata:          metadata,
+		SubAccountAddress: config.Params.SubAccountAddress.Hex(),
+		Message:           result.Message,
+		OutputTxnHash:     result.TxHash,
+		CreatedAt:         time.Now().UTC(),
+	})
+}
+
+func (s *StateActivity) Options() workflow.ActivityOptions {
+	return workflow.ActivityOptions{
+		TaskQueue: entity.BaseTaskQueue,
+		RetryPolicy: &temporal.RetryPolicy{
+			MaximumInterval:        time.Second * 30,
+			MaximumAttempts:        5,
+			NonRetryableErrorTypes: []string{},
+		},
+		StartToCloseTimeout: time.Minute,
+	}
+}
+
+func parseSubscriptionID(subID string) (uuid.UUID, error) {
+	id, err := uuid.Parse(subID)
+	if err != nil {
+		return uuid.UUID{}, temporal.NewNonRetryableApplicationError(
+			fmt.Sprintf("invalid subscription id %q", subID),
+			_errTypeInvalidSubscriptionID,
+			err,
+		)
+	}
+
+	return id, nil
+}
+
+func rawMetadata(metadata entity.Jsonb) (json.RawMessage, error) {
+	switch v := metadata.(type) {
+	case nil:
+		return nil, nil
+	case []byte:
+		return v, nil
+	case json.RawMessage:
+		return v, nil
+	case string:
+		return json.RawMessage(v), nil
+	default:
+		return json.Marshal(v)
+	}
+}
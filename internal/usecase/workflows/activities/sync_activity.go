@@ -0,0 +1,62 @@
+package activities
+
+import (
+	"context"
+	"time"
+
+	"github.com/Brahma-fi/brahma-builder/internal/entity"
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+)
+
+type SubscriptionSyncer interface {
+	SyncTargets(ctx context.Context) ([]entity.SyncTarget, error)
+	SyncExecutor(
+		ctx context.Context,
+		target entity.SyncTarget,
+		progress func(entity.SyncProgress),
+	) (*entity.SyncReport, error)
+}
+
+// SyncActivity exposes each step of the subscription sync as an activity
+type SyncActivity struct {
+	syncer SubscriptionSyncer
+}
+
+func NewSyncActivity(syncer SubscriptionSyncer) *SyncActivity {
+	return &SyncActivity{syncer: syncer}
+}
+
+func (s *SyncActivity) SyncTargets(ctx context.Context) ([]entity.SyncTarget, error) {
+	return s.syncer.SyncTargets(ctx)
+}
+
+// SyncExecutor syncs the subscriptions of target, heartbeating its progress. A retry after a partial
+// apply re-diffs the executor's schedules and applies the remaining mutations only.
+func (s *SyncActivity) SyncExecutor(ctx context.Context, target entity.SyncTarget) (*entity.SyncReport, error) {
+	return s.syncer.SyncExecutor(ctx, target, func(progress entity.SyncProgress) {
+		activity.RecordHeartbeat(ctx, progress)
+	})
+}
+
+func (s *SyncActivity) Options() workflow.ActivityOptions {
+	return workflow.ActivityOptions{
+		TaskQueue: entity.BaseTaskQueue,
+		RetryPolicy: &temporal.RetryPolicy{
+			MaximumInterval:        time.Second * 30,
+			MaximumAttempts:        3,
+			NonRetryableErrorTypes: []string{},
+		},
+		StartToCloseTimeout: time.Minute * 5,
+	}
+}
+
+// ExecutorOptions are the options of SyncExecutor, which may outlast Options on a large executor
+// as long as it keeps heartbeating
+func (s *SyncActivity) ExecutorOptions() workflow.ActivityOptions {
+	options := s.Options()
+	options.StartToCloseTimeout = time.Minute * 30
+	options.HeartbeatTimeout = time.Minute * 5
+	return options
+}
@@ -0,0 +1,149 @@
+package activities
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Brahma-fi/brahma-builder/internal/entity"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	geth "github.com/ethereum/go-ethereum/core/types"
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+)
+
+const (
+	_taskPollInterval = 5 * time.Second
+	// _txDropTimeout is how long a submitted transaction may go without a receipt before
+	// it is checked for having been dropped
+	_txDropTimeout = 10 * time.Minute
+)
+
+// TaskActivity follows a task relayed through the console until its transaction is mined
+type TaskActivity struct {
+	console taskStatusReader
+	rpc     rpcFactory
+}
+
+func NewTaskActivity(console taskStatusReader, rpc rpcFactory) *TaskActivity {
+	return &TaskActivity{console: console, rpc: rpc}
+}
+
+// AwaitTaskConfirmation polls the console until the task's transaction is submitted, then polls the chain
+// until the transaction is mined, reverted or dropped. A txHash already known from the task's webhook skips
+// the console. Progress is heartbeated so a retry resumes where the previous attempt stopped.
+func (t *TaskActivity) AwaitTaskConfirmation(
+	ctx context.Context,
+	taskID string,
+	chainID int64,
+	txHash string,
+) (*entity.ExecutionResult, error) {
+	logger := activity.GetLogger(ctx)
+	client, err := t.rpc.RetryableClient(chainID)
+	if err != nil {
+		return nil, err
+	}
+
+	progress := &taskProgress{Result: entity.ExecutionResult{TaskID: taskID, ChainID: chainID, TxHash: txHash}}
+	if txHash != "" {
+		progress.SubmittedAt = time.Now()
+	}
+
+	if activity.HasHeartbeatDetails(ctx) {
+		// only what the previous attempt recorded replaces the txHash passed in
+		var recorded taskProgress
+		if err = activity.GetHeartbeatDetails(ctx, &recorded); err != nil {
+			logger.Warn("failed to resume task progress", "taskID", taskID, "error", err)
+		} else if recorded.Result.TxHash != "" {
+			progress.Result.TxHash, progress.SubmittedAt = recorded.Result.TxHash, recorded.SubmittedAt
+		}
+	}
+
+	ticker := time.NewTicker(_taskPollInterval)
+	defer ticker.Stop()
+	for {
+		if progress.Result.TxHash == "" {
+			t.pollTask(ctx, progress)
+		}
+
+		if progress.Result.TxHash != "" {
+			if receipt, err := client.TransactionReceipt(ctx, common.HexToHash(progress.Result.TxHash)); err == nil {
+				return receiptResult(progress.Result, receipt), nil
+			}
+
+			if time.Since(progress.SubmittedAt) > _txDropTimeout {
+				_, _, err = client.TransactionByHash(ctx, common.HexToHash(progress.Result.TxHash))
+				switch {
+				case errors.Is(err, ethereum.NotFound):
+					logger.Warn("transaction not found by any upstream", "taskID", taskID, "txHash", progress.Result.TxHash)
+					progress.Result.TxStatus = entity.TxStatusDropped
+					return &progress.Result, nil
+				case err != nil:
+					logger.Warn("failed to look up transaction, retrying", "taskID", taskID, "txHash", progress.Result.TxHash, "error", err)
+				}
+			}
+		}
+
+		if progress.Result.TxStatus == entity.TxStatusFailed {
+			return &progress.Result, nil
+		}
+
+		activity.RecordHeartbeat(ctx, progress)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+type taskProgress struct {
+	Result      entity.ExecutionResult `json:"result"`
+	SubmittedAt time.Time              `json:"submittedAt"`
+}
+
+func (t *TaskActivity) pollTask(ctx context.Context, progress *taskProgress) {
+	logger := activity.GetLogger(ctx)
+	status, err := t.console.TaskStatus(ctx, progress.Result.TaskID)
+	if err != nil {
+		logger.Warn("failed to get task status", "taskID", progress.Result.TaskID, "error", err)
+		return
+	}
+
+	switch {
+	case status.TxHash != "":
+		progress.Result.TxHash = status.TxHash
+		progress.SubmittedAt = time.Now()
+	case status.Status == entity.TaskStateFailed || status.Status == entity.TaskStateCancelled:
+		progress.Result.TxStatus = entity.TxStatusFailed
+		progress.Result.Message = status.Error
+	}
+}
+
+func receiptResult(result entity.ExecutionResult, receipt *geth.Receipt) *entity.ExecutionResult {
+	result.TxStatus = entity.TxStatusConfirmed
+	if receipt.Status != geth.ReceiptStatusSuccessful {
+		result.TxStatus = entity.TxStatusReverted
+	}
+
+	if receipt.BlockNumber != nil {
+		result.BlockNumber = receipt.BlockNumber.Uint64()
+	}
+	result.GasUsed = receipt.GasUsed
+	return &result
+}
+
+func (t *TaskActivity) Options() workflow.ActivityOptions {
+	return workflow.ActivityOptions{
+		TaskQueue: entity.BaseTaskQueue,
+		RetryPolicy: &temporal.RetryPolicy{
+			MaximumInterval:        time.Second * 30,
+			MaximumAttempts:        5,
+			NonRetryableErrorTypes: []string{},
+		},
+		StartToCloseTimeout: time.Minute * 30,
+		HeartbeatTimeout:    time.Minute,
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/Brahma-fi/brahma-builder/internal/entity"
+	"github.com/Brahma-fi/brahma-builder/internal/usecase/strategies"
 	"github.com/ethereum/go-ethereum/common"
 	"go.temporal.io/sdk/workflow"
 )
@@ -34,6 +35,38 @@ type contextActivity interface {
 	activityOptions
 }
 
+type taskActivity interface {
+	AwaitTaskConfirmation(
+		ctx context.Context,
+		taskID string,
+		chainID int64,
+		txHash string,
+	) (*entity.ExecutionResult, error)
+	activityOptions
+}
+
+type stateActivity interface {
+	LatestState(ctx context.Context, subID string) (*entity.ExecutionState, error)
+	SaveExecution(ctx context.Context, config entity.ExecuteWorkflowParams, result entity.ExecutionResult) error
+	activityOptions
+}
+
+type lockActivity interface {
+	RequestLock(ctx context.Context, mutexID string, req entity.LockRequest) error
+	activityOptions
+}
+
+type strategyRegistry interface {
+	ByID(id string) (strategies.Strategy, error)
+}
+
 type configRepo interface {
 	Config(executor common.Address) (*entity.ExecutorConfig, error)
 }
+
+type syncActivity interface {
+	SyncTargets(ctx context.Context) ([]entity.SyncTarget, error)
+	SyncExecutor(ctx context.Context, target entity.SyncTarget) (*entity.SyncReport, error)
+	ExecutorOptions() workflow.ActivityOptions
+	activityOptions
+}
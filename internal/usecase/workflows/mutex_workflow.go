@@ -0,0 +1,138 @@
+package workflows
+
+import (
+	"slices"
+	"time"
+
+	"github.com/Brahma-fi/brahma-builder/internal/entity"
+	"github.com/Brahma-fi/brahma-builder/pkg/log"
+	"go.temporal.io/sdk/workflow"
+)
+
+const (
+	// _mutexIdleTimeout is how long a mutex without holder nor waiters lives before completing
+	_mutexIdleTimeout = 10 * time.Minute
+	// _mutexMaxHistoryLength bounds the mutex's history, it continues as new past it once the lock is free
+	_mutexMaxHistoryLength = 10_000
+)
+
+// SubAccountMutex serialises the executions of a sub-account, whichever schedule, trigger or executor starts them
+type SubAccountMutex struct{}
+
+// MutexWorkflow grants the lock to queued LockRequests one at a time, in the order they were received.
+// A holder releases the lock with SignalLockRelease, or loses it once its lease expires without
+// being renewed with SignalLockRenew.
+func (m SubAccountMutex) MutexWorkflow(ctx workflow.Context, queue []entity.LockRequest) error {
+	logger := workflow.GetLogger(ctx)
+	mutexID := workflow.GetInfo(ctx).WorkflowExecution.ID
+	acquire := workflow.GetSignalChannel(ctx, entity.SignalLockAcquire)
+	release := workflow.GetSignalChannel(ctx, entity.SignalLockRelease)
+	renew := workflow.GetSignalChannel(ctx, entity.SignalLockRenew)
+
+	var holder *entity.LockRequest
+	var cancelTimer workflow.CancelFunc
+	var timer workflow.Future
+	resetTimer := func(d time.Duration) {
+		if cancelTimer != nil {
+			cancelTimer()
+		}
+		var timerCtx workflow.Context
+		timerCtx, cancelTimer = workflow.WithCancel(ctx)
+		timer = workflow.NewTimer(timerCtx, d)
+	}
+	resetTimer(_mutexIdleTimeout)
+
+	for {
+		for holder == nil && len(queue) != 0 {
+			next := queue[0]
+			queue = queue[1:]
+			grant := entity.LockGrant{
+				MutexWorkflowID: mutexID,
+				OwnerRunID:      next.OwnerRunID,
+				ExpiresAt:       workflow.Now(ctx).Add(next.Lease),
+			}
+			if err := workflow.SignalExternalWorkflow(
+				ctx,
+				next.OwnerWorkflowID,
+				next.OwnerRunID,
+				entity.SignalLockGranted,
+				grant,
+			).Get(ctx, nil); err != nil {
+				// the owner completed while waiting, the lock goes to the next request
+				logger.Warn("failed to grant lock", log.Str("owner", next.OwnerWorkflowID), log.Err(err))
+				continue
+			}
+
+			holder = &next
+			resetTimer(next.Lease)
+			logger.Info("lock granted", log.Str("owner", next.OwnerWorkflowID), log.Str("runID", next.OwnerRunID))
+		}
+
+		if holder == nil && len(queue) == 0 && workflow.GetInfo(ctx).GetCurrentHistoryLength() > _mutexMaxHistoryLength {
+			return workflow.NewContinueAsNewError(ctx, m.MutexWorkflow, drainLockRequests(acquire, nil))
+		}
+
+		timedOut := false
+		selector := workflow.NewSelector(ctx)
+		selector.AddReceive(acquire, func(c workflow.ReceiveChannel, _ bool) {
+			req := entity.LockRequest{}
+			c.Receive(ctx, &req)
+			if (holder != nil && holder.OwnerRunID == req.OwnerRunID) ||
+				slices.ContainsFunc(queue, func(r entity.LockRequest) bool { return r.OwnerRunID == req.OwnerRunID }) {
+				return
+			}
+			queue = append(queue, req)
+		})
+		selector.AddReceive(release, func(c workflow.ReceiveChannel, _ bool) {
+			var ownerRunID string
+			c.Receive(ctx, &ownerRunID)
+			if holder != nil && holder.OwnerRunID == ownerRunID {
+				logger.Info("lock released", log.Str("owner", holder.OwnerWorkflowID))
+				holder = nil
+				resetTimer(_mutexIdleTimeout)
+				return
+			}
+			queue = slices.DeleteFunc(queue, func(r entity.LockRequest) bool { return r.OwnerRunID == ownerRunID })
+		})
+		selector.AddReceive(renew, func(c workflow.ReceiveChannel, _ bool) {
+			var ownerRunID string
+			c.Receive(ctx, &ownerRunID)
+			// a renewal sent after the lease expired is ignored, the lock may be held by another run by now
+			if holder != nil && holder.OwnerRunID == ownerRunID {
+				resetTimer(holder.Lease)
+			}
+		})
+		selector.AddFuture(timer, func(f workflow.Future) {
+			// a timer cancelled by resetTimer resolves too, only an elapsed one counts
+			timedOut = f.Get(ctx, nil) == nil
+		})
+		selector.Select(ctx)
+
+		switch {
+		case !timedOut:
+		case holder != nil:
+			logger.Warn("lock lease expired", log.Str("owner", holder.OwnerWorkflowID), log.Str("runID", holder.OwnerRunID))
+			holder = nil
+			resetTimer(_mutexIdleTimeout)
+		case len(queue) == 0:
+			// requests received while deciding to complete would otherwise be lost
+			if queue = drainLockRequests(acquire, queue); len(queue) == 0 {
+				logger.Info("mutex idle, completing")
+				return nil
+			}
+			resetTimer(_mutexIdleTimeout)
+		default:
+			resetTimer(_mutexIdleTimeout)
+		}
+	}
+}
+
+func drainLockRequests(acquire workflow.ReceiveChannel, queue []entity.LockRequest) []entity.LockRequest {
+	for {
+		req := entity.LockRequest{}
+		if !acquire.ReceiveAsync(&req) {
+			return queue
+		}
+		queue = append(queue, req)
+	}
+}
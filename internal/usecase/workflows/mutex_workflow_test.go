@@ -0,0 +1,89 @@
+package workflows
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Brahma-fi/brahma-builder/internal/entity"
+	"github.com/stretchr/testify/mock"
+	"go.temporal.io/sdk/testsuite"
+)
+
+func TestMutexWorkflowRenewedLeaseKeepsTheLock(t *testing.T) {
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestWorkflowEnvironment()
+	m := SubAccountMutex{}
+	env.RegisterWorkflow(m.MutexWorkflow)
+
+	start := env.Now()
+	granted := make(map[string]time.Duration)
+	env.OnSignalExternalWorkflow(mock.Anything, mock.Anything, mock.Anything, entity.SignalLockGranted, mock.Anything).
+		Return(func(_, _, runID, _ string, _ interface{}) error {
+			granted[runID] = env.Now().Sub(start)
+			return nil
+		})
+
+	first := entity.LockRequest{OwnerWorkflowID: "first", OwnerRunID: "first-run", Lease: time.Hour}
+	second := entity.LockRequest{OwnerWorkflowID: "second", OwnerRunID: "second-run", Lease: time.Hour}
+	env.RegisterDelayedCallback(func() {
+		env.SignalWorkflow(entity.SignalLockAcquire, second)
+	}, time.Minute)
+	// renewed before its lease expires, and a renewal of a run which doesn't hold the lock is ignored
+	env.RegisterDelayedCallback(func() {
+		env.SignalWorkflow(entity.SignalLockRenew, first.OwnerRunID)
+		env.SignalWorkflow(entity.SignalLockRenew, second.OwnerRunID)
+	}, 30*time.Minute)
+	env.RegisterDelayedCallback(func() {
+		env.SignalWorkflow(entity.SignalLockRelease, first.OwnerRunID)
+	}, 80*time.Minute)
+	env.RegisterDelayedCallback(func() {
+		env.SignalWorkflow(entity.SignalLockRelease, second.OwnerRunID)
+	}, 90*time.Minute)
+
+	env.ExecuteWorkflow(m.MutexWorkflow, []entity.LockRequest{first})
+	if err := env.GetWorkflowError(); err != nil {
+		t.Fatal(err)
+	}
+
+	if granted[first.OwnerRunID] != 0 {
+		t.Fatalf("first run granted after %s", granted[first.OwnerRunID])
+	}
+
+	// without the renewal the lease would have run out after an hour
+	if granted[second.OwnerRunID] != 80*time.Minute {
+		t.Fatalf("second run granted after %s, want once the first released at 80m", granted[second.OwnerRunID])
+	}
+}
+
+func TestMutexWorkflowLeaseExpiresWithoutRenewal(t *testing.T) {
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestWorkflowEnvironment()
+	m := SubAccountMutex{}
+	env.RegisterWorkflow(m.MutexWorkflow)
+
+	start := env.Now()
+	granted := make(map[string]time.Duration)
+	env.OnSignalExternalWorkflow(mock.Anything, mock.Anything, mock.Anything, entity.SignalLockGranted, mock.Anything).
+		Return(func(_, _, runID, _ string, _ interface{}) error {
+			granted[runID] = env.Now().Sub(start)
+			return nil
+		})
+
+	first := entity.LockRequest{OwnerWorkflowID: "first", OwnerRunID: "first-run", Lease: time.Hour}
+	second := entity.LockRequest{OwnerWorkflowID: "second", OwnerRunID: "second-run", Lease: time.Hour}
+	env.RegisterDelayedCallback(func() {
+		env.SignalWorkflow(entity.SignalLockAcquire, second)
+	}, time.Minute)
+	env.RegisterDelayedCallback(func() {
+		env.SignalWorkflow(entity.SignalLockRelease, second.OwnerRunID)
+	}, 90*time.Minute)
+
+	env.ExecuteWorkflow(m.MutexWorkflow, []entity.LockRequest{first})
+	if err := env.GetWorkflowError(); err != nil {
+		t.Fatal(err)
+	}
+
+	if granted[second.OwnerRunID] != time.Hour {
+		t.Fatalf("second run granted after %s, want once the first's lease expired at 1h", granted[second.OwnerRunID])
+	}
+}
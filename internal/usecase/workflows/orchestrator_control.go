@@ -0,0 +1,89 @@
+package workflows
+
+import (
+	"github.com/Brahma-fi/brahma-builder/internal/entity"
+	"github.com/Brahma-fi/brahma-builder/pkg/log"
+	"go.temporal.io/sdk/workflow"
+)
+
+// runState is the state of an orchestrator run exposed to operators through queries,
+// along with the interventions they signalled.
+type runState struct {
+	phase       entity.ExecutionPhase
+	lastError   string
+	scheduleCtx *entity.ScheduleCtx
+	output      *entity.ExecutionResult
+
+	skip  *entity.OperatorSignal
+	abort *entity.OperatorSignal
+	force *entity.OperatorSignal
+}
+
+func (s *runState) setError(err error) {
+	if err != nil {
+		s.lastError = err.Error()
+	}
+}
+
+// registerRunControls registers the run's query handlers and starts receiving operator signals.
+// An abort cancels ctx, cancelling every activity and timer the run is waiting on.
+func registerRunControls(ctx workflow.Context, state *runState, cancel workflow.CancelFunc) error {
+	if err := workflow.SetQueryHandler(ctx, entity.QueryPhase, func() (entity.ExecutionPhase, error) {
+		return state.phase, nil
+	}); err != nil {
+		return err
+	}
+
+	if err := workflow.SetQueryHandler(ctx, entity.QueryLastError, func() (string, error) {
+		return state.lastError, nil
+	}); err != nil {
+		return err
+	}
+
+	if err := workflow.SetQueryHandler(ctx, entity.QueryScheduleCtx, func() (*entity.ScheduleCtx, error) {
+		return state.scheduleCtx, nil
+	}); err != nil {
+		return err
+	}
+
+	if err := workflow.SetQueryHandler(ctx, entity.QueryStrategyOutput, func() (*entity.ExecutionResult, error) {
+		return state.output, nil
+	}); err != nil {
+		return err
+	}
+
+	logger := workflow.GetLogger(ctx)
+	skip := workflow.GetSignalChannel(ctx, entity.SignalSkip)
+	abort := workflow.GetSignalChannel(ctx, entity.SignalAbort)
+	force := workflow.GetSignalChannel(ctx, entity.SignalForceExecute)
+	receive := func(name string, target **entity.OperatorSignal) func(workflow.ReceiveChannel, bool) {
+		return func(c workflow.ReceiveChannel, _ bool) {
+			signal := &entity.OperatorSignal{}
+			c.Receive(ctx, signal)
+			logger.Info(
+				"received operator signal",
+				log.Str("signal", name),
+				log.Str("operator", signal.Operator),
+				log.Str("reason", signal.Reason),
+				log.Str("phase", string(state.phase)),
+			)
+			*target = signal
+		}
+	}
+
+	workflow.Go(ctx, func(ctx workflow.Context) {
+		selector := workflow.NewSelector(ctx)
+		selector.AddReceive(skip, receive(entity.SignalSkip, &state.skip))
+		selector.AddReceive(abort, receive(entity.SignalAbort, &state.abort))
+		selector.AddReceive(force, receive(entity.SignalForceExecute, &state.force))
+		for ctx.Err() == nil {
+			selector.Select(ctx)
+			if state.abort != nil {
+				cancel()
+				return
+			}
+		}
+	})
+
+	return nil
+}
@@ -0,0 +1,151 @@
+package workflows
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Brahma-fi/brahma-builder/internal/entity"
+	"github.com/Brahma-fi/brahma-builder/pkg/log"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+)
+
+const (
+	_errTypeLockTimeout = "SubAccountLockTimeout"
+	_errTypeLockExpired = "SubAccountLockExpired"
+)
+
+// subAccountLock is the mutex of the run's sub-account, held until release is called. Its lease is renewed
+// meanwhile, so that it covers executing the strategy and confirming its task however long they take.
+type subAccountLock struct {
+	mutexID string
+	// expiresAt is when the lease expires at the earliest, as of its last renewal
+	expiresAt time.Time
+	release   func()
+}
+
+// held fails once the lease expired without being renewed, the mutex may have handed the lock over by then.
+// It happens when the run made no progress for a whole lease, e.g. while no worker was running.
+func (l *subAccountLock) held(ctx workflow.Context) error {
+	if workflow.Now(ctx).Before(l.expiresAt) {
+		return nil
+	}
+
+	return temporal.NewApplicationError(
+		fmt.Sprintf("sub-account lock %s expired at %s", l.mutexID, l.expiresAt),
+		_errTypeLockExpired,
+	)
+}
+
+// acquireLock waits up to timeout for the mutex of the run's sub-account. The returned lock must be
+// released once the run no longer touches the sub-account, a request which was not granted is withdrawn.
+func (o *Orchestrator) acquireLock(
+	ctx workflow.Context,
+	config entity.ExecuteWorkflowParams,
+	timeout time.Duration,
+	lease time.Duration,
+) (*subAccountLock, error) {
+	logger := workflow.GetLogger(ctx)
+	info := workflow.GetInfo(ctx)
+	mutexID := entity.MutexWorkflowID(config.Params.ChainID, config.Params.SubAccountAddress)
+	req := entity.LockRequest{
+		OwnerWorkflowID: info.WorkflowExecution.ID,
+		OwnerRunID:      info.WorkflowExecution.RunID,
+		Lease:           lease,
+	}
+
+	lockActivityCtx := workflow.WithActivityOptions(ctx, o.lockActivity.Options())
+	if err := workflow.ExecuteActivity(lockActivityCtx, o.lockActivity.RequestLock, mutexID, req).Get(ctx, nil); err != nil {
+		return nil, err
+	}
+
+	release := func() {
+		// released even when the run was cancelled, the next run would otherwise wait on the lease
+		releaseCtx, _ := workflow.NewDisconnectedContext(ctx)
+		if err := workflow.SignalExternalWorkflow(
+			releaseCtx,
+			mutexID,
+			"",
+			entity.SignalLockRelease,
+			req.OwnerRunID,
+		).Get(releaseCtx, nil); err != nil {
+			logger.Warn("failed to release sub-account lock", log.Str("mutexID", mutexID), log.Err(err))
+		}
+	}
+
+	grant := awaitLockGrant(ctx, req.OwnerRunID, timeout)
+	if grant == nil {
+		release()
+		return nil, temporal.NewApplicationError(
+			fmt.Sprintf("sub-account lock %s not acquired within %s", mutexID, timeout),
+			_errTypeLockTimeout,
+		)
+	}
+
+	logger.Info("sub-account lock acquired", log.Str("mutexID", mutexID), log.Any("expiresAt", grant.ExpiresAt))
+	lock := &subAccountLock{mutexID: mutexID, expiresAt: grant.ExpiresAt}
+	renewCtx, stopRenewing := workflow.WithCancel(ctx)
+	workflow.Go(renewCtx, func(ctx workflow.Context) {
+		renewLock(ctx, lock, req)
+	})
+	lock.release = func() {
+		stopRenewing()
+		release()
+	}
+
+	return lock, nil
+}
+
+// renewLock renews the lease of lock halfway through, until ctx is cancelled or the lease was let expire
+func renewLock(ctx workflow.Context, lock *subAccountLock, req entity.LockRequest) {
+	for workflow.Sleep(ctx, req.Lease/2) == nil {
+		if lock.held(ctx) != nil {
+			return
+		}
+
+		renewedAt := workflow.Now(ctx)
+		if err := workflow.SignalExternalWorkflow(
+			ctx,
+			lock.mutexID,
+			"",
+			entity.SignalLockRenew,
+			req.OwnerRunID,
+		).Get(ctx, nil); err != nil {
+			workflow.GetLogger(ctx).Warn("failed to renew sub-account lock", log.Str("mutexID", lock.mutexID), log.Err(err))
+			continue
+		}
+
+		// the mutex restarts the lease once it receives the renewal, which is no earlier than it was sent
+		lock.expiresAt = renewedAt.Add(req.Lease)
+	}
+}
+
+// awaitLockGrant waits for the mutex to grant the lock to ownerRunID, nil when it did not within timeout
+func awaitLockGrant(ctx workflow.Context, ownerRunID string, timeout time.Duration) *entity.LockGrant {
+	grants := workflow.GetSignalChannel(ctx, entity.SignalLockGranted)
+	timerCtx, cancelTimer := workflow.WithCancel(ctx)
+	defer cancelTimer()
+	timer := workflow.NewTimer(timerCtx, timeout)
+
+	for {
+		var grant *entity.LockGrant
+		timedOut := false
+		selector := workflow.NewSelector(ctx)
+		selector.AddReceive(grants, func(c workflow.ReceiveChannel, _ bool) {
+			received := entity.LockGrant{}
+			c.Receive(ctx, &received)
+			grant = &received
+		})
+		selector.AddFuture(timer, func(_ workflow.Future) {
+			timedOut = true
+		})
+		selector.Select(ctx)
+
+		switch {
+		case timedOut:
+			return nil
+		case grant.OwnerRunID == ownerRunID:
+			return grant
+		}
+	}
+}
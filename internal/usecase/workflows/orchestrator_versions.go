@@ -0,0 +1,36 @@
+package workflows
+
+import (
+	"github.com/Brahma-fi/brahma-builder/internal/usecase/strategies"
+	"go.temporal.io/sdk/workflow"
+)
+
+// Change IDs of the commands an orchestrator run issues. Schedules keep runs in flight through every
+// deployment, and a run started before a change replays its workflow.DefaultVersion branch. A change to the
+// activities, timers, markers or upserts of a run gets a new change ID, or bumps the max version of an
+// existing one, and keeps the previous branch until no run in retention depends on it.
+const (
+	// _changeExecutionStatus upserts the run's ExecutionStatus search attribute and its result memo
+	_changeExecutionStatus = "execution-status"
+	// _changeExecutionState loads the subscription's state before executing and saves it once completed.
+	// Runs which were skipped, failed or not confirmed are recorded without state.
+	_changeExecutionState = "execution-state"
+	// _changeExecutionGuard waits on an operator while other executions of the schedule are running,
+	// for the GuardTimeout of the executor
+	_changeExecutionGuard = "execution-guard"
+	// _changeTypedStrategies runs the strategy's registered activity rather than the ExecutionHandler one
+	_changeTypedStrategies = "typed-strategies"
+	// _changeTaskTracking awaits the on chain confirmation of the task the strategy submitted
+	_changeTaskTracking = "task-tracking"
+	// _changeSubAccountMutex holds the sub-account's mutex from executing the strategy until its task is mined,
+	// renewing its lease meanwhile
+	_changeSubAccountMutex = "sub-account-mutex"
+)
+
+// _legacyExecutionHandler is the activity every strategy was registered as before _changeTypedStrategies
+const _legacyExecutionHandler = strategies.LegacyActivityName
+
+// changed is true when the run executes with changeID applied
+func changed(ctx workflow.Context, changeID string) bool {
+	return workflow.GetVersion(ctx, changeID, workflow.DefaultVersion, 1) != workflow.DefaultVersion
+}
@@ -2,34 +2,150 @@ package workflows
 
 import (
 	"errors"
+	"fmt"
+	"time"
 
 	"github.com/Brahma-fi/brahma-builder/internal/entity"
 	"github.com/Brahma-fi/brahma-builder/pkg/log"
+	"go.temporal.io/sdk/temporal"
 	"go.temporal.io/sdk/workflow"
 )
 
+const (
+	_errTypeExecutionNotConfirmed = "ExecutionNotConfirmed"
+	_errTypeExecutionAborted      = "ExecutionAborted"
+	_memoKeyExecutionResult       = "executionResult"
+	// _taskUpdateTimeout is how long a run waits on the task's webhook before falling back to polling
+	_taskUpdateTimeout = 5 * time.Minute
+)
+
 type Orchestrator struct {
-	ctxActivity contextActivity
-	config      configRepo
+	ctxActivity  contextActivity
+	taskActivity taskActivity
+	// stateActivity carries strategy state from one execution of a subscription to the next
+	stateActivity stateActivity
+	// lockActivity serialises the runs of a sub-account, see SubAccountMutex
+	lockActivity lockActivity
+	config       configRepo
+	strategies   strategyRegistry
+	// webhooks is true when executors hand the console a callback URL, see awaitTaskUpdate
+	webhooks bool
 }
 
 func NewOrchestrator(
 	ctxActivity contextActivity,
+	taskActivity taskActivity,
+	stateActivity stateActivity,
+	lockActivity lockActivity,
 	cfg configRepo,
+	strategies strategyRegistry,
+	webhooks bool,
 ) *Orchestrator {
 	return &Orchestrator{
-		ctxActivity: ctxActivity,
-		config:      cfg,
+		ctxActivity:   ctxActivity,
+		taskActivity:  taskActivity,
+		stateActivity: stateActivity,
+		lockActivity:  lockActivity,
+		config:        cfg,
+		strategies:    strategies,
+		webhooks:      webhooks,
 	}
 }
 
-func (o *Orchestrator) OrchestratorWorkflow(ctx workflow.Context, config entity.ExecuteWorkflowParams) error {
+func (o *Orchestrator) OrchestratorWorkflow(
+	ctx workflow.Context,
+	config entity.ExecuteWorkflowParams,
+) (*entity.ExecutionResult, error) {
 	logger := workflow.GetLogger(ctx)
 	workflowInfo := workflow.GetInfo(ctx)
 	logger.Info("starting orchestratorWorkflow", log.Str("workflowID", workflowInfo.WorkflowExecution.ID))
 
+	trackStatus := changed(ctx, _changeExecutionStatus)
+	upsertStatus := func(status entity.ExecutionStatus, result *entity.ExecutionResult) error {
+		if !trackStatus {
+			return nil
+		}
+		return upsertExecutionStatus(ctx, status, result)
+	}
+
+	if err := upsertStatus(entity.ExecutionStatusRunning, nil); err != nil {
+		return nil, err
+	}
+
+	state := &runState{phase: entity.ExecutionPhaseFetchingContext}
+	runCtx, cancel := workflow.WithCancel(ctx)
+	defer cancel()
+	if err := registerRunControls(runCtx, state, cancel); err != nil {
+		return nil, err
+	}
+
+	result, err := o.run(runCtx, config, state)
+	switch {
+	case state.abort != nil:
+		state.phase = entity.ExecutionPhaseAborted
+		logger.Warn(
+			"orchestratorWorkflow aborted",
+			log.Str("operator", state.abort.Operator),
+			log.Str("reason", state.abort.Reason),
+			log.Str("workflowID", workflowInfo.WorkflowExecution.ID),
+		)
+		abortErr := temporal.NewNonRetryableApplicationError(
+			fmt.Sprintf("aborted by %s: %s", state.abort.Operator, state.abort.Reason),
+			_errTypeExecutionAborted,
+			err,
+		)
+		o.recordRun(ctx, config, state.output, abortErr)
+		if err := upsertStatus(entity.ExecutionStatusCanceled, state.output); err != nil {
+			return nil, err
+		}
+		return nil, abortErr
+	case err != nil:
+		state.phase = entity.ExecutionPhaseFailed
+		state.setError(err)
+		o.recordRun(ctx, config, state.output, err)
+		if err := upsertStatus(entity.ExecutionStatusFailed, state.output); err != nil {
+			return nil, err
+		}
+		return nil, err
+	case result.Status == entity.ExecutionStatusSkipped:
+		o.recordRun(ctx, config, result, nil)
+	}
+
+	if err = upsertStatus(result.Status, result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// upsertExecutionStatus makes the run's status searchable and keeps its result, if any, in the run's memo
+func upsertExecutionStatus(ctx workflow.Context, status entity.ExecutionStatus, result *entity.ExecutionResult) error {
+	if result != nil {
+		result.Status = status
+		if err := workflow.UpsertMemo(ctx, map[string]interface{}{_memoKeyExecutionResult: result}); err != nil {
+			return fmt.Errorf("failed to upsert execution result: %w", err)
+		}
+	}
+
+	if err := workflow.UpsertTypedSearchAttributes(
+		ctx,
+		temporal.NewSearchAttributeKeyKeyword(entity.SearchAttrKeyExecutionStatus).ValueSet(string(status)),
+	); err != nil {
+		return fmt.Errorf("failed to upsert execution status: %w", err)
+	}
+
+	return nil
+}
+
+func (o *Orchestrator) run(
+	ctx workflow.Context,
+	config entity.ExecuteWorkflowParams,
+	state *runState,
+) (*entity.ExecutionResult, error) {
+	logger := workflow.GetLogger(ctx)
+	workflowInfo := workflow.GetInfo(ctx)
 	if config.Schedule == nil {
-		return errors.New("workflow is not scheduled")
+		return nil, errors.New("workflow is not scheduled")
 	}
 
 	fetchCtxActivityCtx := workflow.WithActivityOptions(ctx, o.ctxActivity.Options())
@@ -44,8 +160,9 @@ func (o *Orchestrator) OrchestratorWorkflow(ctx workflow.Context, config entity.
 			log.Err(err),
 			log.Str("workflowID", workflowInfo.WorkflowExecution.ID),
 		)
-		return err
+		return nil, err
 	}
+	state.scheduleCtx = scheduleCtx
 
 	executorConfig, err := o.config.Config(config.Params.ExecutorAddress)
 	if err != nil {
@@ -54,7 +171,7 @@ func (o *Orchestrator) OrchestratorWorkflow(ctx workflow.Context, config entity.
 			log.Err(err),
 			log.Str("workflowID", workflowInfo.WorkflowExecution.ID),
 		)
-		return err
+		return nil, err
 	}
 
 	activityOpts, err := executorConfig.ActivityOptions()
@@ -64,27 +181,345 @@ func (o *Orchestrator) OrchestratorWorkflow(ctx workflow.Context, config entity.
 			log.Err(err),
 			log.Str("workflowID", workflowInfo.WorkflowExecution.ID),
 		)
-		return err
+		return nil, err
 	}
 
+	strategy, err := o.strategies.ByID(executorConfig.ID)
+	if err != nil {
+		logger.Error(
+			"failed to resolve strategy",
+			log.Err(err),
+			log.Str("executorID", executorConfig.ID),
+			log.Str("workflowID", workflowInfo.WorkflowExecution.ID),
+		)
+		return nil, err
+	}
+
+	var prevState *entity.ExecutionState
+	if changed(ctx, _changeExecutionState) {
+		stateActivityCtx := workflow.WithActivityOptions(ctx, o.stateActivity.Options())
+		if err = workflow.ExecuteActivity(
+			stateActivityCtx,
+			o.stateActivity.LatestState,
+			config.Params.Subscription.Id,
+		).Get(ctx, &prevState); err != nil {
+			logger.Error(
+				"failed to get execution state",
+				log.Err(err),
+				log.Str("workflowID", workflowInfo.WorkflowExecution.ID),
+			)
+			return nil, err
+		}
+	}
+
+	guardTimeout, err := o.guardTimeout(ctx, executorConfig, scheduleCtx)
+	if err != nil {
+		return nil, err
+	}
+
+	if guardTimeout > 0 {
+		// another execution of the schedule may be moving the same funds, wait for an operator to decide
+		state.phase = entity.ExecutionPhaseGuarded
+		logger.Warn(
+			"other executions are running",
+			log.Any("running", scheduleCtx.RunningExecutionWorkflowIDs),
+			log.Str("workflowID", workflowInfo.WorkflowExecution.ID),
+		)
+		if _, err = workflow.AwaitWithTimeout(ctx, guardTimeout, func() bool {
+			return state.force != nil || state.skip != nil
+		}); err != nil {
+			return nil, err
+		}
+
+		if state.force == nil && state.skip == nil {
+			return skipped(state, config, "other executions are running"), nil
+		}
+	}
+
+	if state.skip != nil {
+		return skipped(state, config, fmt.Sprintf("skipped by %s: %s", state.skip.Operator, state.skip.Reason)), nil
+	}
+
+	if changed(ctx, _changeSubAccountMutex) {
+		lockTimeout, lockLease, err := executorConfig.LockOptions()
+		if err != nil {
+			return nil, err
+		}
+
+		state.phase = entity.ExecutionPhaseLocking
+		lock, err := o.acquireLock(ctx, config, lockTimeout, lockLease)
+		if err != nil {
+			logger.Error(
+				"failed to acquire sub-account lock",
+				log.Err(err),
+				log.Str("workflowID", workflowInfo.WorkflowExecution.ID),
+			)
+			return nil, err
+		}
+		defer lock.release()
+
+		if err = lock.held(ctx); err != nil {
+			logger.Error(
+				"sub-account lock expired before executing",
+				log.Err(err),
+				log.Str("workflowID", workflowInfo.WorkflowExecution.ID),
+			)
+			return nil, err
+		}
+	}
+
+	state.phase = entity.ExecutionPhaseExecuting
 	execActivityOptions := workflow.WithActivityOptions(ctx, activityOpts)
-	err = workflow.ExecuteActivity(execActivityOptions, entity.ExecutionHandler, entity.ExecCtx{
+	execCtx := entity.ExecCtx{
 		ScheduleCtx:           *scheduleCtx,
 		ExecuteWorkflowParams: config,
 		TriggeredAt:           workflowInfo.WorkflowStartTime,
-	}).Get(ctx, nil)
+		PrevState:             prevState,
+	}
+	var outcome entity.ExecutionResult
+	if changed(ctx, _changeTypedStrategies) {
+		outcome, err = strategy.Execute(execActivityOptions, execCtx)
+	} else {
+		err = workflow.ExecuteActivity(execActivityOptions, _legacyExecutionHandler, execCtx).Get(ctx, nil)
+	}
 	if err != nil {
 		logger.Error(
-			"executionHandler failed",
+			"strategy execution failed",
 			log.Err(err),
+			log.Str("strategy", strategy.Name()),
 			log.Str("workflowID", workflowInfo.WorkflowExecution.ID),
 		)
-		return err
+		return nil, err
 	}
+	result := &outcome
 
+	result.ChainID = config.Params.ChainID
+	result.Status = entity.ExecutionStatusCompleted
+	if result.Action == "" {
+		result.Action = entity.ExecutionActionNone
+	}
+	state.output = result
+	if result.TaskID == "" || !changed(ctx, _changeTaskTracking) {
+		result.TxStatus = entity.TxStatusNone
+		if err = o.saveExecution(ctx, config, result); err != nil {
+			return nil, err
+		}
+		state.phase = entity.ExecutionPhaseCompleted
+		logger.Info(
+			"orchestratorWorkflow completed without a task",
+			log.Str("workflowID", workflowInfo.WorkflowExecution.ID),
+		)
+		return result, nil
+	}
+
+	state.phase = entity.ExecutionPhaseAwaitingTask
+	confirmed, err := o.awaitTask(ctx, result.TaskID, config.Params.ChainID)
+	if err != nil {
+		logger.Error(
+			"failed to await task confirmation",
+			log.Err(err),
+			log.Str("taskID", result.TaskID),
+			log.Str("workflowID", workflowInfo.WorkflowExecution.ID),
+		)
+		return nil, err
+	}
+
+	result.TxHash = confirmed.TxHash
+	result.BlockNumber = confirmed.BlockNumber
+	result.GasUsed = confirmed.GasUsed
+	result.TxStatus = confirmed.TxStatus
+	if confirmed.Message != "" {
+		result.Message = confirmed.Message
+	}
+
+	if result.TxStatus != entity.TxStatusConfirmed {
+		result.Status = entity.ExecutionStatusFailed
+		logger.Error(
+			"execution was not confirmed",
+			log.Str("taskID", result.TaskID),
+			log.Str("txHash", result.TxHash),
+			log.Str("txStatus", string(result.TxStatus)),
+			log.Str("workflowID", workflowInfo.WorkflowExecution.ID),
+		)
+		// the result is kept as the failure's details so the tx hash is part of the execution history
+		return nil, temporal.NewNonRetryableApplicationError(
+			fmt.Sprintf("task %s %s, tx %s", result.TaskID, result.TxStatus, result.TxHash),
+			_errTypeExecutionNotConfirmed,
+			nil,
+			result,
+		)
+	}
+
+	if err = o.saveExecution(ctx, config, result); err != nil {
+		return nil, err
+	}
+
+	state.phase = entity.ExecutionPhaseCompleted
 	logger.Info(
 		"orchestratorWorkflow completed successfully",
+		log.Str("action", string(result.Action)),
+		log.Str("txHash", result.TxHash),
 		log.Str("workflowID", workflowInfo.WorkflowExecution.ID),
 	)
+	return result, nil
+}
+
+// guardTimeout is how long the run waits on an operator before executing, zero when it doesn't wait.
+// Only runs of executors configured with a GuardTimeout wait, and only while other executions are running.
+func (o *Orchestrator) guardTimeout(
+	ctx workflow.Context,
+	executorConfig *entity.ExecutorConfig,
+	scheduleCtx *entity.ScheduleCtx,
+) (time.Duration, error) {
+	if len(scheduleCtx.RunningExecutionWorkflowIDs) == 0 {
+		return 0, nil
+	}
+
+	if !changed(ctx, _changeExecutionGuard) {
+		return 0, nil
+	}
+
+	return executorConfig.GuardOptions()
+}
+
+// saveExecution persists the execution of the run, the state it carries is handed to the subscription's next run
+func (o *Orchestrator) saveExecution(
+	ctx workflow.Context,
+	config entity.ExecuteWorkflowParams,
+	result *entity.ExecutionResult,
+) error {
+	if !changed(ctx, _changeExecutionState) {
+		return nil
+	}
+
+	stateActivityCtx := workflow.WithActivityOptions(ctx, o.stateActivity.Options())
+	if err := workflow.ExecuteActivity(
+		stateActivityCtx,
+		o.stateActivity.SaveExecution,
+		config,
+		*result,
+	).Get(ctx, nil); err != nil {
+		workflow.GetLogger(ctx).Error(
+			"failed to save execution",
+			log.Err(err),
+			log.Str("workflowID", workflow.GetInfo(ctx).WorkflowExecution.ID),
+		)
+		return err
+	}
+
 	return nil
 }
+
+// recordRun records a run which didn't complete, skipped, failed or not confirmed. Its state is left out so the
+// subscription's next run still starts from the state of the last completed execution.
+func (o *Orchestrator) recordRun(
+	ctx workflow.Context,
+	config entity.ExecuteWorkflowParams,
+	result *entity.ExecutionResult,
+	runErr error,
+) {
+	record := entity.ExecutionResult{}
+	if result != nil {
+		record = *result
+	}
+	record.State = nil
+	if runErr != nil {
+		record.Message = runErr.Error()
+	}
+
+	// the run's outcome doesn't depend on its record, a failure to save it is only logged
+	_ = o.saveExecution(ctx, config, &record)
+}
+
+func skipped(state *runState, config entity.ExecuteWorkflowParams, reason string) *entity.ExecutionResult {
+	state.phase = entity.ExecutionPhaseSkipped
+	state.output = &entity.ExecutionResult{
+		Status:   entity.ExecutionStatusSkipped,
+		Action:   entity.ExecutionActionNone,
+		ChainID:  config.Params.ChainID,
+		TxStatus: entity.TxStatusNone,
+		Reason:   reason,
+		Message:  "skipped",
+	}
+	return state.output
+}
+
+// awaitTask follows the task until its transaction is mined. When webhooks are enabled the console's
+// task updates are awaited first, the confirmation activity then only has to fetch the receipt.
+func (o *Orchestrator) awaitTask(
+	ctx workflow.Context,
+	taskID string,
+	chainID int64,
+) (*entity.ExecutionResult, error) {
+	var webhooks bool
+	// recorded so that replays keep the decision of the worker which ran the workflow
+	if err := workflow.SideEffect(ctx, func(_ workflow.Context) interface{} {
+		return o.webhooks
+	}).Get(&webhooks); err != nil {
+		return nil, err
+	}
+
+	txHash := ""
+	if webhooks {
+		status := awaitTaskUpdate(ctx, taskID)
+		switch {
+		case status == nil:
+		case status.TxHash != "":
+			txHash = status.TxHash
+		case status.Status == entity.TaskStateFailed || status.Status == entity.TaskStateCancelled:
+			return &entity.ExecutionResult{
+				TaskID:   taskID,
+				ChainID:  chainID,
+				TxStatus: entity.TxStatusFailed,
+				Message:  status.Error,
+			}, nil
+		}
+	}
+
+	awaitTaskCtx := workflow.WithActivityOptions(ctx, o.taskActivity.Options())
+	confirmed := &entity.ExecutionResult{}
+	if err := workflow.ExecuteActivity(
+		awaitTaskCtx,
+		o.taskActivity.AwaitTaskConfirmation,
+		taskID,
+		chainID,
+		txHash,
+	).Get(ctx, confirmed); err != nil {
+		return nil, err
+	}
+
+	return confirmed, nil
+}
+
+// awaitTaskUpdate waits for the task's webhook to report a submitted transaction or a terminal state,
+// nil when none arrived within _taskUpdateTimeout.
+func awaitTaskUpdate(ctx workflow.Context, taskID string) *entity.TaskStatus {
+	updates := workflow.GetSignalChannel(ctx, entity.SignalTaskUpdate)
+	timerCtx, cancelTimer := workflow.WithCancel(ctx)
+	defer cancelTimer()
+	timer := workflow.NewTimer(timerCtx, _taskUpdateTimeout)
+
+	for {
+		var status *entity.TaskStatus
+		timedOut := false
+		selector := workflow.NewSelector(ctx)
+		selector.AddReceive(updates, func(c workflow.ReceiveChannel, _ bool) {
+			update := entity.TaskStatus{}
+			c.Receive(ctx, &update)
+			status = &update
+		})
+		selector.AddFuture(timer, func(_ workflow.Future) {
+			timedOut = true
+		})
+		selector.Select(ctx)
+
+		switch {
+		case timedOut:
+			return nil
+		case status.TaskID != taskID:
+			continue
+		case status.TxHash != "" || status.Status.IsTerminal():
+			return status
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package workflows
+
+import (
+	"github.com/Brahma-fi/brahma-builder/internal/entity"
+	"github.com/Brahma-fi/brahma-builder/pkg/log"
+	"go.temporal.io/sdk/workflow"
+)
+
+// SubscriptionSync reconciles the schedules of every registered executor with its console subscriptions
+type SubscriptionSync struct {
+	syncActivity syncActivity
+}
+
+func NewSubscriptionSync(syncActivity syncActivity) *SubscriptionSync {
+	return &SubscriptionSync{syncActivity: syncActivity}
+}
+
+// SyncWorkflow syncs every registered executor concurrently, each in its own activity which fetches its
+// subscriptions, diffs them against its schedules and applies the resulting mutations. Only the executors
+// and their reports go through the history. Failures are isolated to the executor, chain or schedule they
+// happened on and collected in the returned report.
+func (s *SubscriptionSync) SyncWorkflow(ctx workflow.Context) (*entity.SyncReport, error) {
+	logger := workflow.GetLogger(ctx)
+	workflowInfo := workflow.GetInfo(ctx)
+	logger.Info("starting syncWorkflow", log.Str("workflowID", workflowInfo.WorkflowExecution.ID))
+
+	report := entity.NewSyncReport(workflow.Now(ctx))
+	targets := make([]entity.SyncTarget, 0)
+	activityCtx := workflow.WithActivityOptions(ctx, s.syncActivity.Options())
+	if err := workflow.ExecuteActivity(activityCtx, s.syncActivity.SyncTargets).Get(ctx, &targets); err != nil {
+		logger.Error("failed to list executors", log.Err(err))
+		return nil, err
+	}
+
+	executorCtx := workflow.WithActivityOptions(ctx, s.syncActivity.ExecutorOptions())
+	syncs := make([]workflow.Future, len(targets))
+	for i, target := range targets {
+		syncs[i] = workflow.ExecuteActivity(executorCtx, s.syncActivity.SyncExecutor, target)
+	}
+
+	for i, target := range targets {
+		executorReport := &entity.SyncReport{}
+		if err := syncs[i].Get(ctx, executorReport); err != nil {
+			logger.Error("failed to sync executor", log.Str("executor", target.ExecutorAddress.Hex()), log.Err(err))
+			report.Fail(entity.SyncReportEntry{
+				Key:    entity.ScheduleKey{ExecutorAddress: target.ExecutorAddress, ChainID: target.ChainID},
+				Reason: err.Error(),
+			})
+			continue
+		}
+
+		report.Merge(executorReport)
+	}
+
+	return s.finish(ctx, report), nil
+}
+
+func (s *SubscriptionSync) finish(ctx workflow.Context, report *entity.SyncReport) *entity.SyncReport {
+	report.FinishedAt = workflow.Now(ctx)
+	workflow.GetLogger(ctx).Info(
+		"syncWorkflow completed",
+		log.Str("workflowID", workflow.GetInfo(ctx).WorkflowExecution.ID),
+		log.Int("created", len(report.Created)),
+		log.Int("updated", len(report.Updated)),
+		log.Int("paused", len(report.Paused)),
+		log.Int("unpaused", len(report.Unpaused)),
+		log.Int("deleted", len(report.Deleted)),
+		log.Int("skipped", len(report.Skipped)),
+		log.Int("failed", len(report.Failed)),
+	)
+	return report
+}
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS executions_log
+(
+    id                 UUID PRIMARY KEY,
+    sub_id             UUID        NOT NULL,
+    chain_id           BIGINT      NOT NULL,
+    metadata           JSONB,
+    subaccount_address TEXT        NOT NULL,
+    message            TEXT        NOT NULL DEFAULT '',
+    output_txn         TEXT        NOT NULL DEFAULT '',
+    created_at         TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS executions_log_sub_id_created_at_idx ON executions_log (sub_id, created_at DESC);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS executions_log_chain_id_created_at_idx;
+DROP INDEX IF EXISTS executions_log_subaccount_created_at_idx;
@@ -0,0 +1,5 @@
+CREATE INDEX IF NOT EXISTS executions_log_subaccount_created_at_idx
+    ON executions_log (subaccount_address, created_at DESC, id DESC);
+
+CREATE INDEX IF NOT EXISTS executions_log_chain_id_created_at_idx
+    ON executions_log (chain_id, created_at DESC, id DESC);
@@ -108,11 +108,13 @@ func (c *Clients) TransactionReceipt(ctx context.Context, txHash common.Hash) (*
 	})
 }
 
+// TransactionByHash returns ethereum.NotFound only when every upstream answered that the transaction is unknown
 func (c *Clients) TransactionByHash(ctx context.Context, txHash common.Hash) (*geth.Transaction, bool, error) {
 	tx, isPending, err := c.primary.TransactionByHash(ctx, txHash)
 	if err == nil {
 		return tx, isPending, nil
 	}
+	notFound := errors.Is(err, ethereum.NotFound)
 
 	logger := log.GetLogger(ctx)
 	logger.Warn("failed to call primary upstream rpc", log.Str("provider", c.primary.ID()), log.Int("chainID", int(c.chainID)))
@@ -122,10 +124,15 @@ func (c *Clients) TransactionByHash(ctx context.Context, txHash common.Hash) (*g
 		if err == nil {
 			return tx, isPending, nil
 		}
+		notFound = notFound && errors.Is(err, ethereum.NotFound)
 
 		logger.Warn("failed to call fallback upstream rpc", log.Str("provider", c.fallbacks[i].ID()), log.Int("chainID", int(c.chainID)))
 	}
 
+	if notFound {
+		return nil, false, ethereum.NotFound
+	}
+
 	logger.Error("failed to call all upstream rpc", log.Int("chainID", int(c.chainID)))
 	return nil, false, ErrFailedToCallAllUpstreams
 }
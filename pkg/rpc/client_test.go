@@ -0,0 +1,52 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	geth "github.com/ethereum/go-ethereum/core/types"
+)
+
+// fakeRawClient answers TransactionByHash with err, every other call panics
+type fakeRawClient struct {
+	RawClient
+	err error
+}
+
+func (f fakeRawClient) TransactionByHash(context.Context, common.Hash) (*geth.Transaction, bool, error) {
+	return nil, false, f.err
+}
+
+func (f fakeRawClient) ID() string {
+	return "fake"
+}
+
+func TestClientsTransactionByHashNotFound(t *testing.T) {
+	unavailable := errors.New("unavailable")
+	tests := []struct {
+		name      string
+		primary   error
+		fallbacks []error
+		want      error
+	}{
+		{name: "not found by every upstream", primary: ethereum.NotFound, fallbacks: []error{ethereum.NotFound}, want: ethereum.NotFound},
+		{name: "primary unavailable", primary: unavailable, fallbacks: []error{ethereum.NotFound}, want: ErrFailedToCallAllUpstreams},
+		{name: "fallback unavailable", primary: ethereum.NotFound, fallbacks: []error{unavailable}, want: ErrFailedToCallAllUpstreams},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clients := &Clients{primary: fakeRawClient{err: tt.primary}}
+			for _, err := range tt.fallbacks {
+				clients.fallbacks = append(clients.fallbacks, fakeRawClient{err: err})
+			}
+
+			if _, _, err := clients.TransactionByHash(context.Background(), common.Hash{}); !errors.Is(err, tt.want) {
+				t.Fatalf("got %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
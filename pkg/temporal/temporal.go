@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"go.temporal.io/api/workflowservice/v1"
+	"go.temporal.io/sdk/activity"
 	"go.temporal.io/sdk/client"
 	"go.temporal.io/sdk/log"
 	"go.temporal.io/sdk/worker"
@@ -43,23 +44,45 @@ func NewClient(ctx context.Context, cfg Config, logger log.Logger) (client.Clien
 	return temporalClient, nil
 }
 
+// NamedActivity is an activity registered under Name instead of its function name
+type NamedActivity struct {
+	Name string
+	Func any
+}
+
 func RunWorkflow(
 	cli client.Client,
 	taskQueue string,
 	options worker.Options,
-	workflowFunc any,
+	workflowFuncs []any,
 	activityFuncs []any,
 ) error {
+	w := NewWorker(cli, taskQueue, options, workflowFuncs, activityFuncs)
+	return w.Run(worker.InterruptCh())
+}
+
+// NewWorker creates a worker of taskQueue with workflowFuncs and activityFuncs registered, without starting it
+func NewWorker(
+	cli client.Client,
+	taskQueue string,
+	options worker.Options,
+	workflowFuncs []any,
+	activityFuncs []any,
+) worker.Worker {
 	w := worker.New(cli, taskQueue, options)
-	if workflowFunc != nil {
-		w.RegisterWorkflow(workflowFunc)
+	for i := range workflowFuncs {
+		w.RegisterWorkflow(workflowFuncs[i])
 	}
 
 	for i := range activityFuncs {
+		if named, ok := activityFuncs[i].(NamedActivity); ok {
+			w.RegisterActivityWithOptions(named.Func, activity.RegisterOptions{Name: named.Name})
+			continue
+		}
 		w.RegisterActivity(activityFuncs[i])
 	}
 
-	return w.Run(worker.InterruptCh())
+	return w
 }
 
 func registerNamespace(ctx context.Context, serviceName string, options client.Options) error {